@@ -0,0 +1,124 @@
+// Package ai hides the differences between chat-completion backends behind
+// a single Provider interface. The backend is chosen per deployment with
+// AI_PROVIDER, see FromEnv.
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	ErrNotConfigured = errors.New("ai provider not configured")
+	ErrUnavailable   = errors.New("ai provider unavailable")
+	ErrBadResponse   = errors.New("ai provider returned an unusable response")
+)
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Usage is the token accounting reported by the backend, when it reports any.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Provider is a chat-completion backend.
+type Provider interface {
+	// Name identifies the backend, e.g. "deepseek", for logs and metrics.
+	Name() string
+	// Chat returns the assistant's complete reply.
+	Chat(ctx context.Context, msgs []Message) (string, Usage, error)
+	// Stream calls fn with each piece of the reply as it arrives. Returning an
+	// error from fn aborts the stream and is returned as is.
+	Stream(ctx context.Context, msgs []Message, fn func(delta string) error) (Usage, error)
+	// JSON asks the backend for a JSON object (using its JSON mode where it
+	// has one) and decodes the reply into v.
+	JSON(ctx context.Context, msgs []Message, v any) (Usage, error)
+}
+
+// FromEnv returns the backend named by AI_PROVIDER:
+//
+//   - "deepseek" (default): DEEPSEEK_API_URL, DEEPSEEK_API_KEY, DEEPSEEK_MODEL
+//   - "openai": any OpenAI-compatible chat completions endpoint, configured
+//     with OPENAI_API_URL, OPENAI_API_KEY (optional for self-hosted servers)
+//     and OPENAI_MODEL
+//   - "ollama": an Ollama-style local server, OLLAMA_URL and OLLAMA_MODEL
+//
+// Replies are cached unless AI_CACHE=off: in memory by default (at most
+// AI_CACHE_SIZE entries), or in MySQL with AI_CACHE=mysql, see InitCache.
+// Entries expire after AI_CACHE_TTL (default 1h).
+//
+// Each of observers wraps the backend, as Observe does, inside the cache, so
+// it sees only the calls that reach the backend and not cache hits.
+func FromEnv(observers ...func(call string, u Usage, err error)) Provider {
+	p := backendFromEnv()
+	model := ""
+	switch b := p.(type) {
+	case *OpenAI:
+		model = b.Model
+	case *Ollama:
+		model = b.Model
+	}
+	for _, fn := range observers {
+		p = Observe(p, fn)
+	}
+	return withCache(p, model)
+}
+
+func backendFromEnv() Provider {
+	switch strings.ToLower(os.Getenv("AI_PROVIDER")) {
+	case "openai":
+		return &OpenAI{
+			ProviderName: "openai",
+			URL:          envOr("OPENAI_API_URL", "https://api.openai.com/v1/chat/completions"),
+			Key:          os.Getenv("OPENAI_API_KEY"),
+			Model:        envOr("OPENAI_MODEL", "gpt-4o-mini"),
+		}
+	case "ollama":
+		return &Ollama{
+			URL:   envOr("OLLAMA_URL", "http://localhost:11434"),
+			Model: envOr("OLLAMA_MODEL", "qwen2.5"),
+		}
+	}
+	return &OpenAI{
+		ProviderName: "deepseek",
+		URL:          envOr("DEEPSEEK_API_URL", "https://api.deepseek.com/v1/chat/completions"),
+		Key:          os.Getenv("DEEPSEEK_API_KEY"),
+		Model:        envOr("DEEPSEEK_MODEL", "deepseek-chat"),
+		RequireKey:   true,
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// DecodeJSON unmarshals the JSON object in a model reply into v. Models
+// often wrap JSON in a Markdown code fence or a sentence of prose, so when
+// the whole reply does not parse, the outermost {...} is tried instead.
+func DecodeJSON(content string, v any) error {
+	content = strings.TrimSpace(content)
+	if err := json.Unmarshal([]byte(content), v); err == nil {
+		return nil
+	}
+	i := strings.Index(content, "{")
+	j := strings.LastIndex(content, "}")
+	if i < 0 || j <= i {
+		return ErrBadResponse
+	}
+	if err := json.Unmarshal([]byte(content[i:j+1]), v); err != nil {
+		return fmt.Errorf("%w: %v", ErrBadResponse, err)
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package ai
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores model replies by content address. Implementations must be
+// safe for concurrent use.
+type Cache interface {
+	Get(key string) (string, bool)
+	Set(key, val string, ttl time.Duration)
+}
+
+var (
+	cacheMu  sync.RWMutex
+	cache    Cache
+	cacheTTL time.Duration
+	// cacheInit reads AI_CACHE, AI_CACHE_SIZE and AI_CACHE_TTL once.
+	cacheInit sync.Once
+)
+
+func loadCache() {
+	cacheInit.Do(func() {
+		cacheMu.Lock()
+		defer cacheMu.Unlock()
+		cacheTTL = time.Hour
+		if v := os.Getenv("AI_CACHE_TTL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("ai cache: bad AI_CACHE_TTL %q: %v", v, err)
+			} else {
+				cacheTTL = d
+			}
+		}
+		if strings.EqualFold(os.Getenv("AI_CACHE"), "off") || cacheTTL <= 0 {
+			return
+		}
+		size, err := strconv.Atoi(os.Getenv("AI_CACHE_SIZE"))
+		if err != nil || size <= 0 {
+			size = 1000
+		}
+		cache = NewLRU(size)
+	})
+}
+
+// InitCache moves the reply cache to MySQL when AI_CACHE=mysql. Without it,
+// or when the table cannot be created, replies are cached in memory.
+func InitCache(db *sql.DB) error {
+	loadCache()
+	if !strings.EqualFold(os.Getenv("AI_CACHE"), "mysql") {
+		return nil
+	}
+	c, err := NewMySQLCache(db)
+	if err != nil {
+		return err
+	}
+	cacheMu.Lock()
+	cache = c
+	cacheMu.Unlock()
+	return nil
+}
+
+func currentCache() (Cache, time.Duration) {
+	loadCache()
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+	return cache, cacheTTL
+}
+
+type cacheHookKey struct{}
+
+// OnCacheLookup returns a context under which cached providers call fn with
+// the result of every cache lookup, before any reply is delivered.
+func OnCacheLookup(ctx context.Context, fn func(hit bool)) context.Context {
+	return context.WithValue(ctx, cacheHookKey{}, fn)
+}
+
+func reportLookup(ctx context.Context, hit bool) {
+	if fn, ok := ctx.Value(cacheHookKey{}).(func(bool)); ok {
+		fn(hit)
+	}
+}
+
+// cached answers repeated requests from the reply cache. The key covers the
+// backend, model, call kind and every message, system prompt included.
+// Hits report no token usage. Only successful replies are stored, and JSON
+// replies only once they parse.
+type cached struct {
+	p     Provider
+	model string
+}
+
+func withCache(p Provider, model string) Provider {
+	if c, _ := currentCache(); c == nil {
+		return p
+	}
+	return &cached{p: p, model: model}
+}
+
+func (c *cached) Name() string { return c.p.Name() }
+
+func (c *cached) key(kind string, msgs []Message) string {
+	b, _ := json.Marshal(struct {
+		Provider string    `json:"provider"`
+		Model    string    `json:"model"`
+		Kind     string    `json:"kind"`
+		Messages []Message `json:"messages"`
+	}{c.p.Name(), c.model, kind, msgs})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *cached) lookup(ctx context.Context, key string) (string, bool) {
+	store, _ := currentCache()
+	val, ok := store.Get(key)
+	reportLookup(ctx, ok)
+	return val, ok
+}
+
+func (c *cached) store(key, val string) {
+	store, ttl := currentCache()
+	store.Set(key, val, ttl)
+}
+
+func (c *cached) Chat(ctx context.Context, msgs []Message) (string, Usage, error) {
+	key := c.key("chat", msgs)
+	if val, ok := c.lookup(ctx, key); ok {
+		return val, Usage{}, nil
+	}
+	s, u, err := c.p.Chat(ctx, msgs)
+	if err == nil {
+		c.store(key, s)
+	}
+	return s, u, err
+}
+
+// Stream replays a hit as a single delta. Chat and Stream share entries, as
+// they return the same reply.
+func (c *cached) Stream(ctx context.Context, msgs []Message, fn func(delta string) error) (Usage, error) {
+	key := c.key("chat", msgs)
+	if val, ok := c.lookup(ctx, key); ok {
+		return Usage{}, fn(val)
+	}
+	var b strings.Builder
+	u, err := c.p.Stream(ctx, msgs, func(delta string) error {
+		b.WriteString(delta)
+		return fn(delta)
+	})
+	if err == nil {
+		c.store(key, b.String())
+	}
+	return u, err
+}
+
+func (c *cached) JSON(ctx context.Context, msgs []Message, v any) (Usage, error) {
+	key := c.key("json", msgs)
+	if val, ok := c.lookup(ctx, key); ok {
+		return Usage{}, DecodeJSON(val, v)
+	}
+	var raw json.RawMessage
+	u, err := c.p.JSON(ctx, msgs, &raw)
+	if err != nil {
+		return u, err
+	}
+	c.store(key, string(raw))
+	return u, DecodeJSON(string(raw), v)
+}
+
+// LRU is an in-memory Cache holding at most size entries.
+type LRU struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // front is most recently used
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	val     string
+	expires time.Time
+}
+
+func NewLRU(size int) *LRU {
+	return &LRU{size: size, order: list.New(), items: map[string]*list.Element{}}
+}
+
+func (l *LRU) Get(key string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.items[key]
+	if !ok {
+		return "", false
+	}
+	e := el.Value.(*lruEntry)
+	if time.Now().After(e.expires) {
+		l.order.Remove(el)
+		delete(l.items, key)
+		return "", false
+	}
+	l.order.MoveToFront(el)
+	return e.val, true
+}
+
+func (l *LRU) Set(key, val string, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	expires := time.Now().Add(ttl)
+	if el, ok := l.items[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.val, e.expires = val, expires
+		l.order.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.order.PushFront(&lruEntry{key: key, val: val, expires: expires})
+	for l.order.Len() > l.size {
+		el := l.order.Back()
+		l.order.Remove(el)
+		delete(l.items, el.Value.(*lruEntry).key)
+	}
+}
+
+// MySQLCache keeps replies in the ai_cache table so they survive restarts
+// and are shared between instances.
+type MySQLCache struct {
+	db   *sql.DB
+	mu   sync.Mutex
+	sets int
+}
+
+func NewMySQLCache(db *sql.DB) (*MySQLCache, error) {
+	_, err := db.Exec(`
+        CREATE TABLE IF NOT EXISTS ai_cache (
+            k CHAR(64) PRIMARY KEY,
+            v MEDIUMTEXT NOT NULL,
+            expires_at TIMESTAMP NOT NULL,
+            KEY idx_expires (expires_at)
+        ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+    `)
+	if err != nil {
+		return nil, err
+	}
+	return &MySQLCache{db: db}, nil
+}
+
+func (m *MySQLCache) Get(key string) (string, bool) {
+	var v string
+	err := m.db.QueryRow("SELECT v FROM ai_cache WHERE k=? AND expires_at > NOW()", key).Scan(&v)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("ai cache db err: %v", err)
+		}
+		return "", false
+	}
+	return v, true
+}
+
+func (m *MySQLCache) Set(key, val string, ttl time.Duration) {
+	secs := int64(ttl / time.Second)
+	_, err := m.db.Exec(`INSERT INTO ai_cache (k, v, expires_at) VALUES (?, ?, NOW() + INTERVAL ? SECOND)
+        ON DUPLICATE KEY UPDATE v=VALUES(v), expires_at=VALUES(expires_at)`, key, val, secs)
+	if err != nil {
+		log.Printf("ai cache db err: %v", err)
+		return
+	}
+	// Purge expired rows now and then rather than on every write.
+	m.mu.Lock()
+	m.sets++
+	purge := m.sets%100 == 0
+	m.mu.Unlock()
+	if purge {
+		if _, err := m.db.Exec("DELETE FROM ai_cache WHERE expires_at <= NOW()"); err != nil {
+			log.Printf("ai cache purge err: %v", err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// Cache hits do not reach observers passed to FromEnv.
+func TestFromEnvObservesOnlyUpstreamCalls(t *testing.T) {
+	var upstream int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&upstream, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"你好"}}],"usage":{"prompt_tokens":7,"completion_tokens":3,"total_tokens":10}}`))
+	}))
+	defer srv.Close()
+	t.Setenv("AI_PROVIDER", "openai")
+	t.Setenv("OPENAI_API_URL", srv.URL)
+	t.Setenv("OPENAI_MODEL", "cache-test")
+	if c, _ := currentCache(); c == nil {
+		t.Skip("reply cache disabled")
+	}
+
+	var calls int
+	var total Usage
+	p := FromEnv(func(call string, u Usage, err error) {
+		calls++
+		total.Add(u)
+	})
+	msgs := []Message{{Role: "user", Content: "cache me"}}
+	for i := 0; i < 3; i++ {
+		reply, _, err := p.Chat(context.Background(), msgs)
+		if err != nil || reply != "你好" {
+			t.Fatalf("Chat: %q %v", reply, err)
+		}
+	}
+	if upstream != 1 || calls != 1 || total.TotalTokens != 10 {
+		t.Errorf("upstream %d, observed %d calls and %d tokens; want 1, 1, 10", upstream, calls, total.TotalTokens)
+	}
+}
@@ -0,0 +1,168 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// JSONFields parses a JSON object as it streams in and reports its parts as
+// soon as they are complete: Field for every top-level member and Item for
+// every object inside a top-level array, such as one experience entry. An
+// Item's index is the element's position in the array, counting elements
+// of every type, so it matches the decoded slice.
+// Text before the opening brace (a Markdown fence, say) and after the
+// closing one is ignored. Values are passed on raw; callers decode them.
+type JSONFields struct {
+	Field func(key string, value json.RawMessage) error
+	Item  func(key string, index int, value json.RawMessage) error
+
+	buf       []byte
+	stack     []byte // open '{' and '[', outermost first
+	inStr     bool
+	esc       bool
+	started   bool
+	done      bool
+	expectKey bool // the next string at depth 1 is a member name
+	readKey   bool // the open string is a member name
+	awaitVal  bool // a ':' at depth 1 has been seen, the value not yet
+	keyStart  int
+	valStart  int // start of the current top-level value, or -1
+	itemStart int // start of the current array item, or -1
+	itemIndex int // position of the current array item
+	items     int // elements seen so far in the current top-level array
+	key       string
+}
+
+// Feed consumes the next piece of the reply. It returns the first error
+// returned by a callback.
+func (f *JSONFields) Feed(s string) error {
+	for i := 0; i < len(s); i++ {
+		if err := f.feed(s[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Done reports whether the top-level object has been closed.
+func (f *JSONFields) Done() bool { return f.done }
+
+func (f *JSONFields) feed(c byte) error {
+	if f.done {
+		return nil
+	}
+	if !f.started {
+		if c != '{' {
+			return nil
+		}
+		f.started, f.expectKey, f.valStart, f.itemStart = true, true, -1, -1
+	}
+	f.buf = append(f.buf, c)
+	i := len(f.buf) - 1
+	depth := len(f.stack)
+	if f.inStr {
+		switch {
+		case f.esc:
+			f.esc = false
+		case c == '\\':
+			f.esc = true
+		case c == '"':
+			f.inStr = false
+			if depth != 1 {
+				break
+			}
+			if f.readKey {
+				f.readKey = false
+				if err := json.Unmarshal(f.buf[f.keyStart:], &f.key); err != nil {
+					f.key = ""
+				}
+			} else if f.valStart >= 0 {
+				return f.emitField(f.buf[f.valStart:])
+			}
+		}
+		return nil
+	}
+	switch c {
+	case '"':
+		f.inStr = true
+		if depth == 1 {
+			if f.expectKey {
+				f.expectKey, f.readKey, f.keyStart = false, true, i
+			} else if f.awaitVal {
+				f.awaitVal, f.valStart = false, i
+			}
+		}
+	case '{', '[':
+		if depth == 1 && f.awaitVal {
+			f.awaitVal, f.valStart = false, i
+			if c == '[' {
+				f.items = 0
+			}
+		}
+		if depth == 2 && f.stack[1] == '[' && c == '{' {
+			f.itemStart, f.itemIndex = i, f.items
+		}
+		f.stack = append(f.stack, c)
+	case '}', ']':
+		if depth == 0 {
+			return nil
+		}
+		f.stack = f.stack[:depth-1]
+		switch depth - 1 {
+		case 0:
+			f.done = true
+			if f.valStart >= 0 {
+				return f.emitField(f.buf[f.valStart:i])
+			}
+		case 1:
+			if f.valStart >= 0 {
+				return f.emitField(f.buf[f.valStart:])
+			}
+		case 2:
+			if c == '}' && f.stack[1] == '[' && f.itemStart >= 0 {
+				raw := f.buf[f.itemStart:]
+				f.itemStart = -1
+				if f.Item != nil && json.Valid(raw) {
+					return f.Item(f.key, f.itemIndex, copyRaw(raw))
+				}
+			}
+		}
+	case ',':
+		if depth == 2 && f.stack[1] == '[' {
+			f.items++
+		}
+		if depth == 1 {
+			f.expectKey = true
+			if f.valStart >= 0 {
+				return f.emitField(f.buf[f.valStart:i])
+			}
+		}
+	case ':':
+		if depth == 1 {
+			f.awaitVal = true
+		}
+	case ' ', '\t', '\r', '\n':
+	default:
+		if depth == 1 && f.awaitVal {
+			f.awaitVal, f.valStart = false, i
+		}
+	}
+	return nil
+}
+
+// emitField reports the value starting at valStart, once per member.
+func (f *JSONFields) emitField(raw []byte) error {
+	f.valStart = -1
+	raw = bytes.TrimSpace(raw)
+	if f.Field == nil || f.key == "" || !json.Valid(raw) {
+		return nil
+	}
+	return f.Field(f.key, copyRaw(raw))
+}
+
+func copyRaw(b []byte) json.RawMessage {
+	return append(json.RawMessage(nil), b...)
+}
+
+// Text returns the object seen so far, from its opening brace.
+func (f *JSONFields) Text() string { return string(f.buf) }
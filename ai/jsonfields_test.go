@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// expected decodes the object in reply with encoding/json and returns what
+// JSONFields should report: every member, and every object element of an
+// array member under "key[index]".
+func expected(t *testing.T, reply string) (fields, items map[string]string) {
+	t.Helper()
+	start := bytes.IndexByte([]byte(reply), '{')
+	dec := json.NewDecoder(bytes.NewReader([]byte(reply[start:])))
+	var obj map[string]json.RawMessage
+	if err := dec.Decode(&obj); err != nil {
+		t.Fatalf("test reply does not decode: %v", err)
+	}
+	fields, items = map[string]string{}, map[string]string{}
+	for k, v := range obj {
+		fields[k] = compact(t, v)
+		var arr []json.RawMessage
+		if json.Unmarshal(v, &arr) != nil {
+			continue
+		}
+		for i, el := range arr {
+			if bytes.HasPrefix(el, []byte("{")) {
+				items[k+"["+strconv.Itoa(i)+"]"] = compact(t, el)
+			}
+		}
+	}
+	return fields, items
+}
+
+func compact(t *testing.T, raw []byte) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := json.Compact(&b, raw); err != nil {
+		t.Fatalf("compact %s: %v", raw, err)
+	}
+	return b.String()
+}
+
+func TestJSONFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+	}{
+		{"flat", `{"name":"张三","age":30,"ok":true,"none":null}`},
+		{"fenced", "好的，结果如下：\n```json\n{\n  \"summary\": \"x\",\n  \"n\": -1.5e3\n}\n```\n以上。"},
+		{"escapes", `{"a":"he said \"{[\"","b\\\"":"\\","c":"\u4e2d, ]}"}`},
+		{"items", `{"experience":[{"title":"a","date":"2020-06"},{"title":"b","tags":["x",{"y":1}]}],"summary":"s"}`},
+		{"mixed array", `{"list":[["x"],{"y":1},2,"{",{"z":[1,{"w":2}]}],"after":{"k":[]}}`},
+		{"empty", `{"experience":[],"skills":[ ],"o":{}}`},
+		{"whitespace", "{ \"a\" : [ { \"b\" : 1 } ,\n\t{ \"c\" : 2 } ] , \"d\" : 3 }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantFields, wantItems := expected(t, tt.reply)
+			gotFields, gotItems := map[string]string{}, map[string]string{}
+			f := &JSONFields{
+				Field: func(key string, value json.RawMessage) error {
+					if _, dup := gotFields[key]; dup {
+						t.Errorf("field %q reported twice", key)
+					}
+					gotFields[key] = compact(t, value)
+					return nil
+				},
+				Item: func(key string, index int, value json.RawMessage) error {
+					gotItems[key+"["+strconv.Itoa(index)+"]"] = compact(t, value)
+					return nil
+				},
+			}
+			for i := 0; i < len(tt.reply); i++ {
+				if err := f.Feed(tt.reply[i : i+1]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !f.Done() {
+				t.Error("object not done")
+			}
+			if !reflect.DeepEqual(gotFields, wantFields) {
+				t.Errorf("fields:\n got %v\nwant %v", gotFields, wantFields)
+			}
+			if !reflect.DeepEqual(gotItems, wantItems) {
+				t.Errorf("items:\n got %v\nwant %v", gotItems, wantItems)
+			}
+		})
+	}
+}
+
+// Fields are reported as soon as they are complete, not at the end.
+func TestJSONFieldsIncremental(t *testing.T) {
+	var seen []string
+	f := &JSONFields{
+		Field: func(key string, _ json.RawMessage) error { seen = append(seen, key); return nil },
+		Item: func(key string, index int, _ json.RawMessage) error {
+			seen = append(seen, key+"["+strconv.Itoa(index)+"]")
+			return nil
+		},
+	}
+	steps := []struct {
+		feed string
+		want []string
+	}{
+		{`{"name":"张`, nil},
+		{`三",`, []string{"name"}},
+		{`"experience":[{"title":"a"}`, []string{"name", "experience[0]"}},
+		{`,{"title":"b"}`, []string{"name", "experience[0]", "experience[1]"}},
+		{`]`, []string{"name", "experience[0]", "experience[1]", "experience"}},
+		{`}`, []string{"name", "experience[0]", "experience[1]", "experience"}},
+	}
+	for _, st := range steps {
+		if err := f.Feed(st.feed); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(seen, st.want) {
+			t.Errorf("after %q: %v, want %v", st.feed, seen, st.want)
+		}
+	}
+	if !f.Done() || f.Text() != `{"name":"张三","experience":[{"title":"a"},{"title":"b"}]}` {
+		t.Errorf("Done %v, Text %q", f.Done(), f.Text())
+	}
+}
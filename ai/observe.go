@@ -0,0 +1,34 @@
+package ai
+
+import "context"
+
+// Observe wraps p so that fn sees every call as it returns: its kind
+// ("chat", "stream" or "json"), the usage and the error.
+func Observe(p Provider, fn func(call string, u Usage, err error)) Provider {
+	return &observed{p: p, fn: fn}
+}
+
+type observed struct {
+	p  Provider
+	fn func(call string, u Usage, err error)
+}
+
+func (o *observed) Name() string { return o.p.Name() }
+
+func (o *observed) Chat(ctx context.Context, msgs []Message) (string, Usage, error) {
+	s, u, err := o.p.Chat(ctx, msgs)
+	o.fn("chat", u, err)
+	return s, u, err
+}
+
+func (o *observed) Stream(ctx context.Context, msgs []Message, fn func(delta string) error) (Usage, error) {
+	u, err := o.p.Stream(ctx, msgs, fn)
+	o.fn("stream", u, err)
+	return u, err
+}
+
+func (o *observed) JSON(ctx context.Context, msgs []Message, v any) (Usage, error) {
+	u, err := o.p.JSON(ctx, msgs, v)
+	o.fn("json", u, err)
+	return u, err
+}
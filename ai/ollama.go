@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Ollama talks to the native /api/chat endpoint of an Ollama-style local
+// model server. No API key is involved.
+type Ollama struct {
+	URL   string
+	Model string
+}
+
+func (p *Ollama) Name() string { return "ollama" }
+
+type ollamaResponse struct {
+	Message         Message `json:"message"`
+	Done            bool    `json:"done"`
+	PromptEvalCount int     `json:"prompt_eval_count"`
+	EvalCount       int     `json:"eval_count"`
+	Error           string  `json:"error"`
+}
+
+func (r ollamaResponse) usage() Usage {
+	return Usage{
+		PromptTokens:     r.PromptEvalCount,
+		CompletionTokens: r.EvalCount,
+		TotalTokens:      r.PromptEvalCount + r.EvalCount,
+	}
+}
+
+func (p *Ollama) do(ctx context.Context, payload map[string]any) (*http.Response, error) {
+	if p.URL == "" || p.Model == "" {
+		return nil, ErrNotConfigured
+	}
+	payload["model"] = p.Model
+	b, _ := json.Marshal(payload)
+	url := strings.TrimRight(p.URL, "/") + "/api/chat"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: ollama status %d: %s", ErrUnavailable, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
+
+func (p *Ollama) complete(ctx context.Context, payload map[string]any) (string, Usage, error) {
+	payload["stream"] = false
+	resp, err := p.do(ctx, payload)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer resp.Body.Close()
+	var out ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", Usage{}, fmt.Errorf("%w: %v", ErrBadResponse, err)
+	}
+	if out.Error != "" {
+		return "", Usage{}, fmt.Errorf("%w: %s", ErrBadResponse, out.Error)
+	}
+	return out.Message.Content, out.usage(), nil
+}
+
+func (p *Ollama) Chat(ctx context.Context, msgs []Message) (string, Usage, error) {
+	return p.complete(ctx, map[string]any{"messages": msgs})
+}
+
+func (p *Ollama) JSON(ctx context.Context, msgs []Message, v any) (Usage, error) {
+	content, usage, err := p.complete(ctx, map[string]any{"messages": msgs, "format": "json"})
+	if err != nil {
+		return usage, err
+	}
+	return usage, DecodeJSON(content, v)
+}
+
+// Stream reads Ollama's newline-delimited JSON stream. Token counts arrive
+// on the final message, the one with done set.
+func (p *Ollama) Stream(ctx context.Context, msgs []Message, fn func(delta string) error) (Usage, error) {
+	var usage Usage
+	resp, err := p.do(ctx, map[string]any{"messages": msgs, "stream": true})
+	if err != nil {
+		return usage, err
+	}
+	defer resp.Body.Close()
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return usage, fmt.Errorf("%w: %v", ErrBadResponse, err)
+		}
+		if chunk.Error != "" {
+			return usage, fmt.Errorf("%w: %s", ErrBadResponse, chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			if err := fn(chunk.Message.Content); err != nil {
+				return usage, err
+			}
+		}
+		if chunk.Done {
+			return chunk.usage(), nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return usage, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return usage, nil
+}
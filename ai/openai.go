@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OpenAI talks to any endpoint that implements the OpenAI chat completions
+// API: DeepSeek, OpenAI itself, and self-hosted servers such as vLLM or
+// LM Studio.
+type OpenAI struct {
+	ProviderName string
+	URL          string
+	Key          string
+	Model        string
+	// RequireKey makes a missing Key an ErrNotConfigured instead of sending
+	// the request unauthenticated.
+	RequireKey bool
+}
+
+func (p *OpenAI) Name() string { return p.ProviderName }
+
+type openAIChoice struct {
+	Message Message `json:"message"`
+	Delta   Message `json:"delta"`
+}
+
+type openAIResponse struct {
+	Choices []openAIChoice `json:"choices"`
+	Usage   *Usage         `json:"usage"`
+}
+
+func (p *OpenAI) do(ctx context.Context, payload map[string]any) (*http.Response, error) {
+	if p.URL == "" || (p.RequireKey && p.Key == "") {
+		return nil, ErrNotConfigured
+	}
+	payload["model"] = p.Model
+	b, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, "POST", p.URL, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if p.Key != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Key)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s status %d: %s", ErrUnavailable, p.ProviderName, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
+
+func (p *OpenAI) complete(ctx context.Context, payload map[string]any) (string, Usage, error) {
+	resp, err := p.do(ctx, payload)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer resp.Body.Close()
+	var out openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", Usage{}, fmt.Errorf("%w: %v", ErrBadResponse, err)
+	}
+	var usage Usage
+	if out.Usage != nil {
+		usage = *out.Usage
+	}
+	if len(out.Choices) == 0 {
+		return "", usage, ErrBadResponse
+	}
+	return out.Choices[0].Message.Content, usage, nil
+}
+
+func (p *OpenAI) Chat(ctx context.Context, msgs []Message) (string, Usage, error) {
+	return p.complete(ctx, map[string]any{"messages": msgs})
+}
+
+func (p *OpenAI) JSON(ctx context.Context, msgs []Message, v any) (Usage, error) {
+	content, usage, err := p.complete(ctx, map[string]any{
+		"messages":        msgs,
+		"response_format": map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return usage, err
+	}
+	return usage, DecodeJSON(content, v)
+}
+
+// Stream reads the server-sent events of a streamed completion. Usage is
+// requested through stream_options; servers that ignore it report none.
+func (p *OpenAI) Stream(ctx context.Context, msgs []Message, fn func(delta string) error) (Usage, error) {
+	var usage Usage
+	resp, err := p.do(ctx, map[string]any{
+		"messages":       msgs,
+		"stream":         true,
+		"stream_options": map[string]bool{"include_usage": true},
+	})
+	if err != nil {
+		return usage, err
+	}
+	defer resp.Body.Close()
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return usage, fmt.Errorf("%w: %v", ErrBadResponse, err)
+		}
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+		for _, ch := range chunk.Choices {
+			if ch.Delta.Content == "" {
+				continue
+			}
+			if err := fn(ch.Delta.Content); err != nil {
+				return usage, err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return usage, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return usage, nil
+}
@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Outcomes of a structured request, as reported to clients.
+const (
+	Generated = "generated" // the first reply was usable
+	Repaired  = "repaired"  // a reply was usable after repair rounds
+	Fallback  = "fallback"  // set by callers that gave up and used a default
+)
+
+// Add accumulates the token counts of another call.
+func (u *Usage) Add(o Usage) {
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.TotalTokens += o.TotalTokens
+}
+
+// Structured asks p for a JSON object and decodes it into v, which must be a
+// pointer. The reply has to match v's fields exactly (unknown keys are
+// rejected) and then pass check, if check is not nil. When either fails, the
+// reply and the error are sent back to the model asking for a corrected
+// object, at most repairs times.
+//
+// It returns Generated or Repaired on success. Otherwise the error is the
+// provider's, or wraps ErrBadResponse once the repair rounds are used up;
+// choosing a fallback is left to the caller.
+func Structured(ctx context.Context, p Provider, msgs []Message, v any, check func() error, repairs int) (string, Usage, error) {
+	return structured(ctx, p, msgs, nil, v, check, repairs)
+}
+
+// Finish is Structured for a reply to msgs that was already obtained some
+// other way, typically by streaming it: reply is checked first and only
+// sent back for repair if it fails.
+func Finish(ctx context.Context, p Provider, msgs []Message, reply string, v any, check func() error, repairs int) (string, Usage, error) {
+	raw := json.RawMessage(extractJSON(reply))
+	return structured(ctx, p, msgs, &raw, v, check, repairs)
+}
+
+// extractJSON trims anything around the outermost braces of s, such as a
+// Markdown fence.
+func extractJSON(s string) string {
+	i := strings.Index(s, "{")
+	j := strings.LastIndex(s, "}")
+	if i < 0 || j <= i {
+		return strings.TrimSpace(s)
+	}
+	return s[i : j+1]
+}
+
+// structured implements Structured; when first is not nil it stands in for
+// the reply to the first request.
+func structured(ctx context.Context, p Provider, msgs []Message, first *json.RawMessage, v any, check func() error, repairs int) (string, Usage, error) {
+	var total Usage
+	msgs = append([]Message(nil), msgs...)
+	for attempt := 0; ; attempt++ {
+		var raw json.RawMessage
+		var err error
+		if attempt == 0 && first != nil {
+			raw = *first
+		} else {
+			var usage Usage
+			usage, err = p.JSON(ctx, msgs, &raw)
+			total.Add(usage)
+		}
+		if err == nil {
+			err = decodeStrict(raw, v)
+		}
+		if err == nil && check != nil {
+			if cerr := check(); cerr != nil {
+				err = fmt.Errorf("%w: %v", ErrBadResponse, cerr)
+			}
+		}
+		if err == nil {
+			if attempt == 0 {
+				return Generated, total, nil
+			}
+			return Repaired, total, nil
+		}
+		if !errors.Is(err, ErrBadResponse) || attempt >= repairs {
+			return "", total, err
+		}
+		if len(raw) > 0 {
+			msgs = append(msgs,
+				Message{Role: "assistant", Content: string(raw)},
+				Message{Role: "user", Content: "上面的 JSON 不符合要求：" + problem(err) + "\n请修正这些问题并返回完整的 JSON 对象，只返回 JSON。"})
+		} else {
+			msgs = append(msgs, Message{Role: "user", Content: "上一次回复不是有效的 JSON：" + problem(err) + "\n请重新返回完整的 JSON 对象，只返回 JSON。"})
+		}
+	}
+}
+
+// decodeStrict resets v and decodes raw into it, rejecting unknown keys and
+// values of the wrong type.
+func decodeStrict(raw json.RawMessage, v any) error {
+	rv := reflect.ValueOf(v).Elem()
+	rv.Set(reflect.Zero(rv.Type()))
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrBadResponse, err)
+	}
+	return nil
+}
+
+// problem strips the ErrBadResponse prefix, which means nothing to the model.
+func problem(err error) string {
+	return strings.TrimPrefix(err.Error(), ErrBadResponse.Error()+": ")
+}
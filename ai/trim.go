@@ -0,0 +1,57 @@
+package ai
+
+import "unicode/utf8"
+
+// EstimateTokens guesses the token count of s without a tokenizer: about
+// one token per CJK character and one per four bytes of other text, which
+// errs on the high side for both.
+func EstimateTokens(s string) int {
+	n, other := 0, 0
+	for _, r := range s {
+		if r >= 0x2E80 {
+			n++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+	}
+	return n + (other+3)/4
+}
+
+// messageOverhead approximates the per-message framing tokens.
+const messageOverhead = 4
+
+// Trim drops the oldest conversation turns until msgs fit in maxTokens.
+// System messages and the final message are always kept, and the kept
+// history never starts with an assistant reply. maxTokens <= 0 disables
+// trimming.
+func Trim(msgs []Message, maxTokens int) []Message {
+	if maxTokens <= 0 || len(msgs) == 0 {
+		return msgs
+	}
+	var system, history []Message
+	for _, m := range msgs {
+		if m.Role == "system" {
+			system = append(system, m)
+		} else {
+			history = append(history, m)
+		}
+	}
+	budget := maxTokens
+	for _, m := range system {
+		budget -= EstimateTokens(m.Content) + messageOverhead
+	}
+	// Walk back from the newest message while the budget lasts.
+	start := len(history)
+	for start > 0 {
+		cost := EstimateTokens(history[start-1].Content) + messageOverhead
+		if budget < cost && start < len(history) {
+			break
+		}
+		budget -= cost
+		start--
+	}
+	for start < len(history)-1 && history[start].Role == "assistant" {
+		start++
+	}
+	return append(system, history[start:]...)
+}
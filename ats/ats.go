@@ -0,0 +1,266 @@
+// Package ats estimates how well a resume survives an applicant tracking
+// system. The checks are deterministic and local: no AI call and no network,
+// so the same resume and job description always get the same score.
+package ats
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dongzhiwei-git/resume/keywords"
+	"github.com/dongzhiwei-git/resume/models"
+	"github.com/dongzhiwei-git/resume/plaintext"
+)
+
+// Finding codes.
+const (
+	CodeMissingContact  = "missing_contact"
+	CodeMissingSection  = "missing_section"
+	CodeUnparsableDate  = "unparsable_date"
+	CodeLongBullet      = "long_bullet"
+	CodeMissingKeywords = "missing_keywords"
+	CodeCustomTitle     = "nonstandard_title"
+)
+
+// Severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// MaxBullet is the longest bullet, in characters, that is not reported.
+const MaxBullet = 120
+
+// Penalty caps keep one kind of problem from swamping the score.
+const (
+	maxDatePenalty    = 20
+	maxBulletPenalty  = 15
+	maxTitlePenalty   = 10
+	maxKeywordPenalty = 30
+)
+
+type Finding struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	// Field uses the editor's form paths, as validation does.
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+	// Penalty is the number of points this finding cost.
+	Penalty int `json:"penalty"`
+}
+
+type Result struct {
+	// Score runs from 0 to 100.
+	Score    int       `json:"score"`
+	Findings []Finding `json:"findings"`
+	// Keywords is set when a job description was supplied.
+	Keywords *keywords.Report `json:"keywords,omitempty"`
+}
+
+var (
+	// A year, optionally with a month: 2020, 2020.06, 2020-6, 2020年6月.
+	dateRe = regexp.MustCompile(`(?:19|20)\d{2}(?:[./年-]\d{1,2})?`)
+	// Words ATS parsers understand as an open end date.
+	presentRe = regexp.MustCompile(`(?i)至今|现在|目前|present|now|current`)
+	bulletSep = regexp.MustCompile(`[；;\n]+`)
+)
+
+// Section titles most parsers recognise; custom sections titled otherwise
+// may be merged into the wrong section or dropped.
+var standardTitles = []string{
+	"个人简介", "自我评价", "工作经历", "工作经验", "实习经历", "教育经历", "教育背景",
+	"专业技能", "技能", "项目经历", "项目经验", "资格证书", "证书", "荣誉奖项", "获奖情况",
+	"语言能力", "志愿者经历", "社会实践", "出版物", "论文", "专利", "兴趣爱好",
+	"summary", "profile", "experience", "work experience", "employment", "education",
+	"skills", "projects", "certifications", "certificates", "awards", "honors",
+	"languages", "volunteer", "volunteering", "publications", "patents", "interests",
+}
+
+type analyzer struct {
+	res Result
+	// spent tracks points already deducted per capped code.
+	spent map[string]int
+}
+
+func (a *analyzer) add(code, severity, field, msg string, penalty int) {
+	if limit := capFor(code); limit > 0 {
+		if left := limit - a.spent[code]; penalty > left {
+			penalty = left
+		}
+		a.spent[code] += penalty
+	}
+	a.res.Findings = append(a.res.Findings, Finding{
+		Code: code, Severity: severity, Field: field, Message: msg, Penalty: penalty,
+	})
+	a.res.Score -= penalty
+}
+
+func capFor(code string) int {
+	switch code {
+	case CodeUnparsableDate:
+		return maxDatePenalty
+	case CodeLongBullet:
+		return maxBulletPenalty
+	case CodeCustomTitle:
+		return maxTitlePenalty
+	}
+	return 0
+}
+
+// Analyze scores r. When jd is not blank, keywords from the job description
+// that the resume never mentions cost up to 30 points.
+func Analyze(r models.Resume, jd string) Result {
+	a := &analyzer{res: Result{Score: 100, Findings: []Finding{}}, spent: map[string]int{}}
+
+	contact(a, r)
+	sections(a, r)
+	for i, e := range r.Experience {
+		if blank(e.Title, e.Company, e.Date, e.Description) {
+			continue
+		}
+		p := fmt.Sprintf("experience[%d]", i)
+		date(a, p+".date", e.Date)
+		bullets(a, p+".description", e.Description)
+	}
+	for i, e := range r.Education {
+		if blank(e.Degree, e.School, e.Date) {
+			continue
+		}
+		date(a, fmt.Sprintf("education[%d].date", i), e.Date)
+	}
+	for i, pr := range r.Projects {
+		if blank(pr.Name, pr.Role, pr.Date, pr.URL, pr.Description) {
+			continue
+		}
+		p := fmt.Sprintf("projects[%d]", i)
+		date(a, p+".date", pr.Date)
+		bullets(a, p+".description", pr.Description)
+	}
+	for i, cs := range r.Custom {
+		customTitle(a, fmt.Sprintf("custom_sections[%d].title", i), cs.Title)
+	}
+	if strings.TrimSpace(jd) != "" {
+		text := plaintext.String(r)
+		rep := keywords.Compare(jd, text, text)
+		a.res.Keywords = &rep
+		if n := len(rep.Missing); n > 0 {
+			penalty := int(float64(maxKeywordPenalty)*(1-rep.Coverage) + 0.5)
+			a.add(CodeMissingKeywords, SeverityWarning, "",
+				fmt.Sprintf("职位描述中有 %d 个关键词未出现在简历中：%s", n, strings.Join(rep.Missing, "、")), penalty)
+		}
+	}
+	if a.res.Score < 0 {
+		a.res.Score = 0
+	}
+	return a.res
+}
+
+func contact(a *analyzer, r models.Resume) {
+	if strings.TrimSpace(r.Name) == "" {
+		a.add(CodeMissingContact, SeverityError, "name", "缺少姓名", 15)
+	}
+	if strings.TrimSpace(r.Email) == "" {
+		a.add(CodeMissingContact, SeverityError, "email", "缺少邮箱，招聘系统无法联系到你", 10)
+	}
+	if strings.TrimSpace(r.Phone) == "" {
+		a.add(CodeMissingContact, SeverityWarning, "phone", "缺少电话", 5)
+	}
+}
+
+func sections(a *analyzer, r models.Resume) {
+	hasExp := false
+	for _, e := range r.Experience {
+		if strings.TrimSpace(e.Company+e.Title) != "" {
+			hasExp = true
+			break
+		}
+	}
+	if !hasExp {
+		a.add(CodeMissingSection, SeverityWarning, "experience", "没有工作经历", 10)
+	}
+	hasEdu := false
+	for _, e := range r.Education {
+		if strings.TrimSpace(e.School+e.Degree) != "" {
+			hasEdu = true
+			break
+		}
+	}
+	if !hasEdu {
+		a.add(CodeMissingSection, SeverityWarning, "education", "没有教育经历", 5)
+	}
+	hasSkill := false
+	for _, g := range r.Skills {
+		if len(g.Items) > 0 {
+			hasSkill = true
+			break
+		}
+	}
+	if !hasSkill {
+		a.add(CodeMissingSection, SeverityInfo, "skills", "没有技能栏目，关键词匹配会更依赖经历描述", 3)
+	}
+}
+
+// date reports dates an ATS cannot turn into a start and end: no year at
+// all, or a single year with nothing marking the range as ongoing.
+func date(a *analyzer, field, val string) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		a.add(CodeUnparsableDate, SeverityWarning, field, "缺少时间", 3)
+		return
+	}
+	n := len(dateRe.FindAllString(val, -1))
+	// A range separator counts only outside the dates, so that the "-" of
+	// "2020-06" does not make it a range.
+	rest := dateRe.ReplaceAllString(val, " ")
+	switch {
+	case n == 0:
+		a.add(CodeUnparsableDate, SeverityWarning, field, "无法识别的时间："+val+"，建议写成 2020.06 - 2022.03", 5)
+	case n == 1 && !presentRe.MatchString(val) && strings.ContainsAny(rest, "-–—~至到"):
+		a.add(CodeUnparsableDate, SeverityInfo, field, "时间段只能识别出一个日期："+val, 2)
+	}
+}
+
+func bullets(a *analyzer, field, text string) {
+	for _, b := range bulletSep.Split(text, -1) {
+		b = strings.TrimSpace(b)
+		if n := utf8.RuneCountInString(b); n > MaxBullet {
+			a.add(CodeLongBullet, SeverityInfo, field,
+				fmt.Sprintf("要点过长（%d 字），建议拆分到 %d 字以内：%s…", n, MaxBullet, prefix(b, 20)), 3)
+		}
+	}
+}
+
+func customTitle(a *analyzer, field, title string) {
+	t := strings.ToLower(strings.TrimSpace(title))
+	if t == "" {
+		return
+	}
+	for _, s := range standardTitles {
+		if t == s {
+			return
+		}
+	}
+	a.add(CodeCustomTitle, SeverityInfo, field, "非常见栏目标题「"+title+"」可能无法被识别", 2)
+}
+
+func blank(vals ...string) bool {
+	for _, v := range vals {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func prefix(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
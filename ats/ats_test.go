@@ -0,0 +1,84 @@
+package ats
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dongzhiwei-git/resume/models"
+)
+
+func TestDate(t *testing.T) {
+	tests := []struct {
+		val      string
+		severity string // "" for no finding
+	}{
+		{"2020-06", ""},
+		{"2020.06", ""},
+		{"2020年6月", ""},
+		{"2020.06 - 至今", ""},
+		{"2020-06 - present", ""},
+		{"2014 - 2018", ""},
+		{"2014-09 - 2018-06", ""},
+		{"2014.09~2018.06", ""},
+		{"2014 - ", SeverityInfo},
+		{"2020-06 至", SeverityInfo},
+		{"去年", SeverityWarning},
+		{"", SeverityWarning},
+	}
+	for _, tt := range tests {
+		a := &analyzer{spent: map[string]int{}}
+		date(a, "d", tt.val)
+		got := ""
+		if len(a.res.Findings) > 0 {
+			got = a.res.Findings[0].Severity
+		}
+		if got != tt.severity || len(a.res.Findings) > 1 {
+			t.Errorf("date(%q): got %v, want severity %q", tt.val, a.res.Findings, tt.severity)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	full := models.Resume{
+		Name: "张三", Email: "z@example.com", Phone: "13800000000",
+		Experience: []models.Exp{{Title: "后端工程师", Company: "某公司", Date: "2020-06 - 至今", Description: "负责 Go 服务开发；优化 MySQL 查询"}},
+		Education:  []models.Edu{{Degree: "本科", School: "某大学", Date: "2016 - 2020"}},
+		Skills:     []models.SkillGroup{{Category: "后端", Items: []models.SkillItem{{Name: "Go"}, {Name: "MySQL"}}}},
+	}
+	if res := Analyze(full, ""); res.Score != 100 || len(res.Findings) != 0 {
+		t.Fatalf("complete resume: got %d %v", res.Score, res.Findings)
+	}
+
+	res := Analyze(models.Resume{}, "")
+	if res.Score != 100-15-10-5-10-5-3 {
+		t.Errorf("empty resume: score %d, findings %v", res.Score, res.Findings)
+	}
+
+	long := full
+	long.Experience = []models.Exp{{Company: "某公司", Date: "2020", Description: strings.Repeat("很长", MaxBullet)}}
+	res = Analyze(long, "")
+	if res.Score != 100-3 || res.Findings[0].Code != CodeLongBullet {
+		t.Errorf("long bullet: got %d %v", res.Score, res.Findings)
+	}
+
+	// The penalty cap bounds findings of one kind.
+	many := full
+	many.Experience = nil
+	for i := 0; i < 10; i++ {
+		many.Experience = append(many.Experience, models.Exp{Company: "某公司", Date: "某年"})
+	}
+	if res = Analyze(many, ""); res.Score != 100-maxDatePenalty {
+		t.Errorf("date cap: got %d", res.Score)
+	}
+
+	// Same input, same result.
+	jd := "熟悉 Go、MySQL 与 Kubernetes"
+	first := Analyze(full, jd)
+	if first.Keywords == nil || first.Score >= 100 {
+		t.Errorf("job description: got %d %+v", first.Score, first.Keywords)
+	}
+	if again := Analyze(full, jd); !reflect.DeepEqual(first, again) {
+		t.Errorf("not deterministic: %+v vs %+v", first, again)
+	}
+}
@@ -0,0 +1,315 @@
+// Package conversations stores AI coaching sessions: the system prompt
+// assembled when the session was created and every message since. Like
+// saved resumes, they live in MySQL when a database is configured and in
+// memory otherwise, and are addressed by an unguessable ID. Each records the
+// client that created it, and only that client can list, read, extend or
+// delete it.
+package conversations
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dongzhiwei-git/resume/ai"
+)
+
+var ErrNotFound = errors.New("conversation not found")
+
+type Conversation struct {
+	ID string `json:"id"`
+	// Owner identifies the client that created the conversation, as
+	// handlers.conversationOwner does. It is not sent to clients.
+	Owner string `json:"-"`
+	// System is the assembled system prompt. It is not sent to clients.
+	System    string       `json:"-"`
+	Messages  []ai.Message `json:"messages"`
+	CreatedAt string       `json:"created_at"`
+	UpdatedAt string       `json:"updated_at"`
+}
+
+// Summary describes a conversation in a List.
+type Summary struct {
+	ID string `json:"id"`
+	// Title is the start of the first user message.
+	Title        string `json:"title"`
+	MessageCount int    `json:"message_count"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+}
+
+const timeLayout = "2006-01-02T15:04:05"
+
+// titleLen is the length of Summary.Title in characters.
+const titleLen = 40
+
+var db *sql.DB
+var mu sync.RWMutex
+var mem = map[string]*Conversation{}
+
+func useDB() bool { return db != nil }
+
+func Init(d *sql.DB) error {
+	_, err := d.Exec(`
+        CREATE TABLE IF NOT EXISTS ai_conversations (
+            id CHAR(32) PRIMARY KEY,
+            owner VARCHAR(64) NOT NULL DEFAULT '',
+            system_prompt MEDIUMTEXT NOT NULL,
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            KEY idx_owner (owner, updated_at)
+        ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+    `)
+	if err != nil {
+		return err
+	}
+	if err := addOwnerColumn(d); err != nil {
+		return err
+	}
+	_, err = d.Exec(`
+        CREATE TABLE IF NOT EXISTS ai_messages (
+            id BIGINT AUTO_INCREMENT PRIMARY KEY,
+            conversation_id CHAR(32) NOT NULL,
+            role VARCHAR(16) NOT NULL,
+            content MEDIUMTEXT NOT NULL,
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            KEY idx_conversation (conversation_id, id)
+        ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+    `)
+	if err != nil {
+		return err
+	}
+	db = d
+	return nil
+}
+
+// addOwnerColumn upgrades tables created before conversations had owners.
+// Their conversations keep an empty owner and are listed to nobody.
+func addOwnerColumn(d *sql.DB) error {
+	var n int
+	err := d.QueryRow(`SELECT COUNT(*) FROM information_schema.COLUMNS
+        WHERE TABLE_SCHEMA=DATABASE() AND TABLE_NAME='ai_conversations' AND COLUMN_NAME='owner'`).Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = d.Exec(`ALTER TABLE ai_conversations
+        ADD COLUMN owner VARCHAR(64) NOT NULL DEFAULT '' AFTER id,
+        ADD KEY idx_owner (owner, updated_at)`)
+	return err
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func Create(owner, system string) (Conversation, error) {
+	now := time.Now().Format(timeLayout)
+	conv := Conversation{ID: randomHex(16), Owner: owner, System: system, Messages: []ai.Message{}, CreatedAt: now, UpdatedAt: now}
+	if useDB() {
+		if _, err := db.Exec("INSERT INTO ai_conversations (id, owner, system_prompt) VALUES (?, ?, ?)", conv.ID, owner, system); err != nil {
+			return Conversation{}, err
+		}
+		return conv, nil
+	}
+	mu.Lock()
+	c := conv
+	mem[conv.ID] = &c
+	mu.Unlock()
+	return conv, nil
+}
+
+// Get returns owner's conversation id. A conversation of another owner is
+// reported as ErrNotFound, and so is every conversation for an empty owner.
+func Get(owner, id string) (Conversation, error) {
+	if owner == "" {
+		return Conversation{}, ErrNotFound
+	}
+	if useDB() {
+		var conv Conversation
+		err := db.QueryRow(`SELECT id, owner, system_prompt, DATE_FORMAT(created_at, '%Y-%m-%dT%H:%i:%s'), DATE_FORMAT(updated_at, '%Y-%m-%dT%H:%i:%s')
+            FROM ai_conversations WHERE id=? AND owner=?`, id, owner).Scan(&conv.ID, &conv.Owner, &conv.System, &conv.CreatedAt, &conv.UpdatedAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return Conversation{}, ErrNotFound
+			}
+			return Conversation{}, err
+		}
+		rows, err := db.Query("SELECT role, content FROM ai_messages WHERE conversation_id=? ORDER BY id", id)
+		if err != nil {
+			return Conversation{}, err
+		}
+		defer rows.Close()
+		conv.Messages = []ai.Message{}
+		for rows.Next() {
+			var m ai.Message
+			if err := rows.Scan(&m.Role, &m.Content); err != nil {
+				return Conversation{}, err
+			}
+			conv.Messages = append(conv.Messages, m)
+		}
+		return conv, rows.Err()
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := mem[id]
+	if !ok || c.Owner != owner {
+		return Conversation{}, ErrNotFound
+	}
+	conv := *c
+	conv.Messages = append([]ai.Message{}, c.Messages...)
+	return conv, nil
+}
+
+// Append adds msgs to the end of owner's conversation id in one
+// transaction.
+func Append(owner, id string, msgs ...ai.Message) error {
+	if owner == "" {
+		return ErrNotFound
+	}
+	if useDB() {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		var one int
+		if err := tx.QueryRow("SELECT 1 FROM ai_conversations WHERE id=? AND owner=? FOR UPDATE", id, owner).Scan(&one); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+		for _, m := range msgs {
+			if _, err := tx.Exec("INSERT INTO ai_messages (conversation_id, role, content) VALUES (?, ?, ?)", id, m.Role, m.Content); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec("UPDATE ai_conversations SET updated_at=NOW() WHERE id=?", id); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	c, ok := mem[id]
+	if !ok || c.Owner != owner {
+		return ErrNotFound
+	}
+	c.Messages = append(c.Messages, msgs...)
+	c.UpdatedAt = time.Now().Format(timeLayout)
+	return nil
+}
+
+// List returns owner's conversations, most recently updated first, skipping
+// offset and returning at most limit, together with the total count. An
+// empty owner has none.
+func List(owner string, limit, offset int) ([]Summary, int, error) {
+	list := []Summary{}
+	if owner == "" {
+		return list, 0, nil
+	}
+	if useDB() {
+		var total int
+		if err := db.QueryRow("SELECT COUNT(*) FROM ai_conversations WHERE owner=?", owner).Scan(&total); err != nil {
+			return nil, 0, err
+		}
+		rows, err := db.Query(`SELECT c.id,
+                COALESCE((SELECT content FROM ai_messages m WHERE m.conversation_id=c.id AND m.role='user' ORDER BY m.id LIMIT 1), ''),
+                (SELECT COUNT(*) FROM ai_messages m WHERE m.conversation_id=c.id),
+                DATE_FORMAT(c.created_at, '%Y-%m-%dT%H:%i:%s'), DATE_FORMAT(c.updated_at, '%Y-%m-%dT%H:%i:%s')
+            FROM ai_conversations c WHERE c.owner=? ORDER BY c.updated_at DESC, c.id LIMIT ? OFFSET ?`, owner, limit, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var s Summary
+			if err := rows.Scan(&s.ID, &s.Title, &s.MessageCount, &s.CreatedAt, &s.UpdatedAt); err != nil {
+				return nil, 0, err
+			}
+			s.Title = title(s.Title)
+			list = append(list, s)
+		}
+		return list, total, rows.Err()
+	}
+	mu.RLock()
+	for _, c := range mem {
+		if c.Owner != owner {
+			continue
+		}
+		s := Summary{ID: c.ID, MessageCount: len(c.Messages), CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt}
+		for _, m := range c.Messages {
+			if m.Role == "user" {
+				s.Title = title(m.Content)
+				break
+			}
+		}
+		list = append(list, s)
+	}
+	mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].UpdatedAt != list[j].UpdatedAt {
+			return list[i].UpdatedAt > list[j].UpdatedAt
+		}
+		return list[i].ID < list[j].ID
+	})
+	total := len(list)
+	if offset > total {
+		offset = total
+	}
+	if end := offset + limit; end < total {
+		list = list[:end]
+	}
+	return list[offset:], total, nil
+}
+
+func title(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= titleLen {
+		return s
+	}
+	return string([]rune(s)[:titleLen]) + "…"
+}
+
+// Delete removes owner's conversation id and its messages in one
+// transaction.
+func Delete(owner, id string) error {
+	if owner == "" {
+		return ErrNotFound
+	}
+	if useDB() {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		res, err := tx.Exec("DELETE FROM ai_conversations WHERE id=? AND owner=?", id, owner)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		if _, err := tx.Exec("DELETE FROM ai_messages WHERE conversation_id=?", id); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if c, ok := mem[id]; !ok || c.Owner != owner {
+		return ErrNotFound
+	}
+	delete(mem, id)
+	return nil
+}
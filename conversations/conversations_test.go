@@ -0,0 +1,113 @@
+package conversations
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dongzhiwei-git/resume/ai"
+)
+
+func TestListScopedAndPaged(t *testing.T) {
+	var ids []string
+	for i := 0; i < 5; i++ {
+		conv, err := Create("ip:192.0.2.1", "system")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, conv.ID)
+	}
+	if _, err := Create("ip:192.0.2.2", "system"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Append("ip:192.0.2.1", ids[0], ai.Message{Role: "user", Content: "  帮我\n改一下简历" + strings.Repeat("好", 50)}, ai.Message{Role: "assistant", Content: "好的"}); err != nil {
+		t.Fatal(err)
+	}
+
+	all, total, err := List("ip:192.0.2.1", 100, 0)
+	if err != nil || total != 5 || len(all) != 5 {
+		t.Fatalf("List: %d of %d, %v", len(all), total, err)
+	}
+	seen := map[string]bool{}
+	for _, s := range all {
+		seen[s.ID] = true
+	}
+	for _, id := range ids {
+		if !seen[id] {
+			t.Errorf("%s missing from the owner's list", id)
+		}
+	}
+	for _, s := range all {
+		if s.ID == ids[0] {
+			if s.MessageCount != 2 || !strings.HasPrefix(s.Title, "帮我 改一下简历") || !strings.HasSuffix(s.Title, "…") {
+				t.Errorf("summary: %+v", s)
+			}
+		}
+	}
+
+	var paged []Summary
+	for offset := 0; offset < 6; offset += 2 {
+		page, total, err := List("ip:192.0.2.1", 2, offset)
+		if err != nil || total != 5 {
+			t.Fatalf("page at %d: total %d, %v", offset, total, err)
+		}
+		paged = append(paged, page...)
+	}
+	if len(paged) != 5 {
+		t.Fatalf("pages returned %d conversations", len(paged))
+	}
+	for i := range paged {
+		if paged[i].ID != all[i].ID {
+			t.Errorf("page order differs at %d", i)
+		}
+	}
+	if page, _, _ := List("ip:192.0.2.1", 2, 10); len(page) != 0 {
+		t.Errorf("offset past the end: %v", page)
+	}
+	if page, total, _ := List("ip:192.0.2.3", 10, 0); len(page) != 0 || total != 0 {
+		t.Errorf("stranger sees %v", page)
+	}
+
+	if err := Delete("ip:192.0.2.1", ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Get("ip:192.0.2.1", ids[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: %v", err)
+	}
+	if err := Delete("ip:192.0.2.1", ids[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete: %v", err)
+	}
+	if _, total, _ := List("ip:192.0.2.1", 10, 0); total != 4 {
+		t.Errorf("total after Delete: %d", total)
+	}
+}
+
+// Other owners, and the empty owner of rows from before owners were
+// recorded, cannot reach a conversation even with its ID.
+func TestOwnerEnforced(t *testing.T) {
+	conv, err := Create("owner:a", "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, owner := range []string{"owner:b", ""} {
+		if _, err := Get(owner, conv.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get as %q: %v", owner, err)
+		}
+		if err := Append(owner, conv.ID, ai.Message{Role: "user", Content: "hi"}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Append as %q: %v", owner, err)
+		}
+		if err := Delete(owner, conv.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Delete as %q: %v", owner, err)
+		}
+	}
+	if _, total, _ := List("", 10, 0); total != 0 {
+		t.Errorf("empty owner lists %d", total)
+	}
+	got, err := Get("owner:a", conv.ID)
+	if err != nil || len(got.Messages) != 0 {
+		t.Errorf("owner's Get: %+v, %v", got, err)
+	}
+	if err := Delete("owner:a", conv.ID); err != nil {
+		t.Errorf("owner's Delete: %v", err)
+	}
+}
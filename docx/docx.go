@@ -0,0 +1,367 @@
+package docx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dongzhiwei-git/resume/models"
+)
+
+// Write renders r as an Office Open XML (.docx) document.
+func Write(w io.Writer, r models.Resume) error {
+	color := hexColor(r.Config.Color)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"word/_rels/document.xml.rels", documentRels},
+		{"word/styles.xml", styles(color, fontSize(r.Config.FontSize), r.Config.Font)},
+		{"word/numbering.xml", numbering},
+		{"word/document.xml", document(r, color)},
+	}
+	zw := zip.NewWriter(w)
+	for _, p := range parts {
+		f, err := zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+const contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>
+<Override PartName="/word/numbering.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"/>
+</Types>`
+
+const rootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`
+
+const documentRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering" Target="numbering.xml"/>
+</Relationships>`
+
+const numbering = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:abstractNum w:abstractNumId="0"><w:multiLevelType w:val="singleLevel"/>
+<w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="bullet"/><w:lvlText w:val="•"/><w:lvlJc w:val="left"/>
+<w:pPr><w:ind w:left="360" w:hanging="240"/></w:pPr></w:lvl>
+</w:abstractNum>
+<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>
+</w:numbering>`
+
+func styles(color string, size int, font string) string {
+	latin, eastAsia := "Calibri", "Microsoft YaHei"
+	if font != "" {
+		latin, eastAsia = esc(font), esc(font)
+	}
+	sz := strconv.Itoa(size)
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:docDefaults>
+<w:rPrDefault><w:rPr><w:rFonts w:ascii="` + latin + `" w:hAnsi="` + latin + `" w:eastAsia="` + eastAsia + `" w:cs="` + latin + `"/><w:color w:val="333333"/><w:sz w:val="` + sz + `"/><w:szCs w:val="` + sz + `"/><w:lang w:val="en-US" w:eastAsia="zh-CN"/></w:rPr></w:rPrDefault>
+<w:pPrDefault><w:pPr><w:spacing w:after="60" w:line="300" w:lineRule="auto"/></w:pPr></w:pPrDefault>
+</w:docDefaults>
+<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>
+<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>
+<w:pPr><w:spacing w:after="60"/></w:pPr><w:rPr><w:b/><w:color w:val="` + color + `"/><w:sz w:val="` + strconv.Itoa(size*2+8) + `"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>
+<w:pPr><w:keepNext/><w:pBdr><w:bottom w:val="single" w:sz="4" w:space="1" w:color="EEEEEE"/></w:pBdr><w:spacing w:before="240" w:after="120"/><w:outlineLvl w:val="0"/></w:pPr>
+<w:rPr><w:b/><w:caps/><w:color w:val="` + color + `"/><w:sz w:val="` + strconv.Itoa(size+6) + `"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>
+<w:pPr><w:keepNext/><w:spacing w:before="120" w:after="0"/><w:outlineLvl w:val="1"/></w:pPr>
+<w:rPr><w:b/><w:color w:val="222222"/><w:sz w:val="` + strconv.Itoa(size+2) + `"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="ListBullet"><w:name w:val="List Bullet"/><w:basedOn w:val="Normal"/><w:qFormat/>
+<w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr><w:spacing w:after="0"/></w:pPr></w:style>
+</w:styles>`
+}
+
+func document(r models.Resume, color string) string {
+	b := &strings.Builder{}
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>`)
+
+	header(b, r, color)
+	tab := textWidth(r.Config.PaperSize)
+
+	for _, sec := range r.Sections() {
+		section(b, r, sec, tab)
+	}
+
+	b.WriteString(sectPr(r.Config.PaperSize))
+	b.WriteString(`</w:body></w:document>`)
+	return b.String()
+}
+
+// section writes one resume section; empty sections are skipped.
+func section(b *strings.Builder, r models.Resume, sec models.Section, tab int) {
+	switch sec.Key {
+	case models.SectionCustom:
+		cs := sec.Custom
+		if len(cs.Items) == 0 {
+			return
+		}
+		heading(b, cs.Title, r.Config.Template)
+		for _, it := range cs.Items {
+			if it.Heading != "" || it.Date != "" {
+				item(b, it.Heading, it.Date, tab)
+			}
+			if it.Subtitle != "" {
+				para(b, "", "", run(it.Subtitle, `<w:b/><w:color w:val="555555"/>`))
+			}
+			for _, line := range Bullets(it.Body) {
+				para(b, "", "", run(line, ""))
+			}
+		}
+	case models.SectionSummary:
+		if s := strings.TrimSpace(r.Summary); s != "" {
+			heading(b, "个人简介", r.Config.Template)
+			for _, line := range strings.Split(s, "\n") {
+				para(b, "", "", run(line, ""))
+			}
+		}
+	case models.SectionSkills:
+		var skills []models.SkillGroup
+		for _, g := range r.Skills {
+			if len(g.Items) > 0 {
+				skills = append(skills, g)
+			}
+		}
+		if len(skills) > 0 {
+			heading(b, "专业技能", r.Config.Template)
+			for _, g := range skills {
+				runs := ""
+				if g.Category != "" {
+					runs = run(g.Category+"：", `<w:b/>`)
+				}
+				para(b, "", "", runs+run(g.DisplayText(), ""))
+			}
+		}
+	case models.SectionExperience:
+		var exps []models.Exp
+		for _, e := range r.Experience {
+			if e.Company != "" {
+				exps = append(exps, e)
+			}
+		}
+		if len(exps) > 0 {
+			heading(b, "工作经历", r.Config.Template)
+			for _, e := range exps {
+				item(b, e.Title, e.Date, tab)
+				para(b, "", "", run(e.Company, `<w:b/><w:color w:val="555555"/>`))
+				for _, bullet := range Bullets(e.Description) {
+					para(b, "ListBullet", "", run(bullet, ""))
+				}
+			}
+		}
+	case models.SectionProjects:
+		var projs []models.Project
+		for _, p := range r.Projects {
+			if p.Name != "" {
+				projs = append(projs, p)
+			}
+		}
+		if len(projs) > 0 {
+			heading(b, "项目经历", r.Config.Template)
+			for _, p := range projs {
+				item(b, p.Name, p.Date, tab)
+				if sub := strings.TrimSpace(p.Role + "  " + p.URL); sub != "" {
+					para(b, "", "", run(sub, `<w:b/><w:color w:val="555555"/>`))
+				}
+				for _, bullet := range Bullets(p.Description) {
+					para(b, "ListBullet", "", run(bullet, ""))
+				}
+			}
+		}
+	case models.SectionEducation:
+		var edus []models.Edu
+		for _, e := range r.Education {
+			if e.School != "" {
+				edus = append(edus, e)
+			}
+		}
+		if len(edus) > 0 {
+			heading(b, "教育背景", r.Config.Template)
+			for _, e := range edus {
+				item(b, e.School, e.Date, tab)
+				para(b, "", "", run(e.Degree, `<w:b/><w:color w:val="555555"/>`))
+			}
+		}
+	case models.SectionCertifications:
+		var certs []models.Certification
+		for _, c := range r.Certifications {
+			if c.Name != "" {
+				certs = append(certs, c)
+			}
+		}
+		if len(certs) > 0 {
+			heading(b, "资格证书", r.Config.Template)
+			for _, c := range certs {
+				item(b, c.Name, c.Date, tab)
+				if c.Issuer != "" {
+					para(b, "", "", run(c.Issuer, `<w:color w:val="555555"/>`))
+				}
+			}
+		}
+	case models.SectionAwards:
+		var awards []models.Award
+		for _, a := range r.Awards {
+			if a.Title != "" {
+				awards = append(awards, a)
+			}
+		}
+		if len(awards) > 0 {
+			heading(b, "荣誉奖项", r.Config.Template)
+			for _, a := range awards {
+				item(b, a.Title, a.Date, tab)
+				if a.Issuer != "" {
+					para(b, "", "", run(a.Issuer, `<w:color w:val="555555"/>`))
+				}
+				for _, line := range Bullets(a.Description) {
+					para(b, "", "", run(line, ""))
+				}
+			}
+		}
+	}
+}
+
+func header(b *strings.Builder, r models.Resume, color string) {
+	contact := r.Email
+	if r.Phone != "" {
+		if contact != "" {
+			contact += "  |  "
+		}
+		contact += r.Phone
+	}
+	switch r.Config.Template {
+	case "modern":
+		shade := `<w:shd w:val="clear" w:color="auto" w:fill="` + color + `"/>`
+		para(b, "Title", shade, run(r.Name, `<w:color w:val="FFFFFF"/>`))
+		para(b, "", shade+`<w:spacing w:after="240"/>`, run(contact, `<w:color w:val="FFFFFF"/>`))
+	case "minimal":
+		para(b, "Title", "", run(r.Name, `<w:b w:val="0"/><w:spacing w:val="40"/>`))
+		para(b, "", `<w:spacing w:after="240"/>`, run(contact, `<w:color w:val="555555"/>`))
+	default:
+		para(b, "Title", `<w:jc w:val="center"/>`, run(r.Name, ""))
+		para(b, "", `<w:pBdr><w:bottom w:val="single" w:sz="12" w:space="6" w:color="`+color+`"/></w:pBdr><w:spacing w:after="240"/><w:jc w:val="center"/>`, run(contact, `<w:color w:val="555555"/>`))
+	}
+}
+
+func heading(b *strings.Builder, title, tpl string) {
+	ppr := ""
+	if tpl == "minimal" {
+		ppr = `<w:pBdr><w:bottom w:val="nil"/></w:pBdr><w:shd w:val="clear" w:color="auto" w:fill="F4F4F4"/>`
+	}
+	para(b, "Heading1", ppr, run(title, ""))
+}
+
+// item writes an entry title with its date pushed to the right margin.
+func item(b *strings.Builder, title, date string, tab int) {
+	runs := run(title, "")
+	if date != "" {
+		runs += `<w:r><w:tab/></w:r>` + run(date, `<w:b w:val="0"/><w:i/><w:color w:val="666666"/><w:sz w:val="18"/>`)
+	}
+	para(b, "Heading2", `<w:tabs><w:tab w:val="right" w:pos="`+strconv.Itoa(tab)+`"/></w:tabs>`, runs)
+}
+
+func para(b *strings.Builder, style, ppr, runs string) {
+	b.WriteString("<w:p>")
+	if style != "" || ppr != "" {
+		b.WriteString("<w:pPr>")
+		if style != "" {
+			b.WriteString(`<w:pStyle w:val="` + style + `"/>`)
+		}
+		b.WriteString(ppr)
+		b.WriteString("</w:pPr>")
+	}
+	b.WriteString(runs)
+	b.WriteString("</w:p>")
+}
+
+func run(text, rpr string) string {
+	if text == "" {
+		return ""
+	}
+	s := "<w:r>"
+	if rpr != "" {
+		s += "<w:rPr>" + rpr + "</w:rPr>"
+	}
+	return s + `<w:t xml:space="preserve">` + esc(text) + "</w:t></w:r>"
+}
+
+const margin = 851 // 15mm in twips
+
+func pageSize(paper string) (int, int) {
+	if strings.EqualFold(paper, "letter") {
+		return 12240, 15840
+	}
+	return 11906, 16838
+}
+
+func textWidth(paper string) int {
+	w, _ := pageSize(paper)
+	return w - 2*margin
+}
+
+func sectPr(paper string) string {
+	w, h := pageSize(paper)
+	m := strconv.Itoa(margin)
+	return `<w:sectPr><w:pgSz w:w="` + strconv.Itoa(w) + `" w:h="` + strconv.Itoa(h) + `"/><w:pgMar w:top="` + m + `" w:right="` + m + `" w:bottom="` + m + `" w:left="` + m + `" w:header="0" w:footer="0" w:gutter="0"/></w:sectPr>`
+}
+
+// Bullets splits a free-form description into bullet points. The AI prompt
+// asks for points separated by Chinese semicolons, while people typing in the
+// editor tend to use one line per point.
+func Bullets(s string) []string {
+	var out []string
+	for _, line := range strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == '；' || r == ';' }) {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*•·"))
+		if line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func fontSize(s string) int {
+	switch s {
+	case "small":
+		return 19
+	case "large":
+		return 23
+	}
+	return 21
+}
+
+func hexColor(s string) string {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) == 6 {
+		if _, err := strconv.ParseUint(s, 16, 32); err == nil {
+			return strings.ToUpper(s)
+		}
+	}
+	return "333333"
+}
+
+func esc(s string) string {
+	var b strings.Builder
+	// xml.EscapeText only fails if the writer does; strings.Builder never does.
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/dongzhiwei-git/resume/ats"
+	"github.com/dongzhiwei-git/resume/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type atsReq struct {
+	Resume         models.Resume `json:"resume"`
+	JobDescription string        `json:"job_description"`
+}
+
+// ApiAtsScore scores a resume for applicant tracking systems. The job
+// description is optional; without it keyword coverage is not checked.
+func ApiAtsScore(c *gin.Context) {
+	var reqBody atsReq
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.String(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	c.JSON(http.StatusOK, ats.Analyze(reqBody.Resume, reqBody.JobDescription))
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dongzhiwei-git/resume/ai"
+	"github.com/dongzhiwei-git/resume/config"
+	"github.com/dongzhiwei-git/resume/conversations"
+	"github.com/dongzhiwei-git/resume/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createConversationReq struct {
+	// Resume, when given, is added to the system prompt so the coach can
+	// refer to it.
+	Resume *models.Resume `json:"resume"`
+}
+
+type appendMessageReq struct {
+	Content string `json:"content"`
+}
+
+// contextTokens is the history budget of a conversation turn, from
+// AI_CONTEXT_TOKENS (default 6000). Older turns beyond it are not sent.
+func contextTokens() int {
+	if n, err := strconv.Atoi(os.Getenv("AI_CONTEXT_TOKENS")); err == nil && n > 0 {
+		return n
+	}
+	return 6000
+}
+
+// ownerCookie holds the random token that identifies the conversations of
+// a caller without an API key.
+const ownerCookie = "ai_conversations"
+
+// conversationOwner identifies whose conversations a request may see: the
+// API key when X-API-Key is one of AI_API_KEYS, otherwise the token in the
+// HttpOnly ownerCookie. Client IPs are not used, since everyone behind one
+// NAT shares an IP. With issue set, a caller without a token is given one;
+// otherwise its owner is empty and it has no conversations. ok is false for
+// an unknown API key.
+func conversationOwner(c *gin.Context, issue bool) (owner string, ok bool) {
+	if strings.TrimSpace(c.GetHeader("X-API-Key")) != "" && os.Getenv("AI_API_KEYS") != "" {
+		return aiClient(c)
+	}
+	token, err := c.Cookie(ownerCookie)
+	if b, herr := hex.DecodeString(token); err != nil || herr != nil || len(b) != 32 {
+		if !issue {
+			return "", true
+		}
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+		token = hex.EncodeToString(b)
+		secure := c.Request.TLS != nil || c.Request.Header.Get("X-Forwarded-Proto") == "https"
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(ownerCookie, token, 365*24*3600, "/api/ai/conversations", "", secure, true)
+	}
+	sum := sha256.Sum256([]byte(token))
+	return "owner:" + hex.EncodeToString(sum[:16]), true
+}
+
+func conversationError(c *gin.Context, err error) {
+	if errors.Is(err, conversations.ErrNotFound) {
+		c.String(http.StatusNotFound, "Conversation not found")
+		return
+	}
+	log.Printf("conversation storage err: %v", err)
+	c.String(http.StatusInternalServerError, "Storage error")
+}
+
+func ApiCreateConversation(c *gin.Context) {
+	if !config.AppConfig.EnableAIAssistant {
+		c.String(http.StatusForbidden, "Disabled")
+		return
+	}
+	var reqBody createConversationReq
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&reqBody); err != nil {
+			c.String(http.StatusBadRequest, "Invalid JSON")
+			return
+		}
+	}
+	system := chatSystemPrompt()
+	if reqBody.Resume != nil {
+		resumeJSON, _ := json.Marshal(reqBody.Resume)
+		system += "\n\n用户当前的简历（JSON）：\n" + string(resumeJSON)
+	}
+	owner, ok := conversationOwner(c, true)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid API key"})
+		return
+	}
+	conv, err := conversations.Create(owner, system)
+	if err != nil {
+		conversationError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, conv)
+}
+
+// maxConversationPage bounds ?limit= of ApiListConversations.
+const maxConversationPage = 100
+
+// ApiListConversations lists the caller's conversations, most recently
+// updated first, paged with ?limit= (default 20) and ?offset=. The caller is
+// identified by conversationOwner.
+func ApiListConversations(c *gin.Context) {
+	owner, ok := conversationOwner(c, false)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid API key"})
+		return
+	}
+	limit, offset := 20, 0
+	var err error
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxConversationPage {
+			c.String(http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			c.String(http.StatusBadRequest, "Invalid offset")
+			return
+		}
+	}
+	list, total, err := conversations.List(owner, limit, offset)
+	if err != nil {
+		conversationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"conversations": list, "total": total, "limit": limit, "offset": offset})
+}
+
+// ApiGetConversation returns one of the caller's conversations; those of
+// other callers are 404, like unknown IDs.
+func ApiGetConversation(c *gin.Context) {
+	owner, ok := conversationOwner(c, false)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid API key"})
+		return
+	}
+	conv, err := conversations.Get(owner, c.Param("id"))
+	if err != nil {
+		conversationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, conv)
+}
+
+func ApiDeleteConversation(c *gin.Context) {
+	owner, ok := conversationOwner(c, false)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid API key"})
+		return
+	}
+	if err := conversations.Delete(owner, c.Param("id")); err != nil {
+		conversationError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ApiAppendConversationMessage sends a user message to one of the caller's
+// conversations with the stored system prompt and as much recent history as
+// fits AI_CONTEXT_TOKENS, then saves both the message and the reply. With ?stream=1 the reply is relayed like
+// ApiAiStream. Nothing is saved when the model call fails.
+func ApiAppendConversationMessage(c *gin.Context) {
+	if !config.AppConfig.EnableAIAssistant {
+		c.String(http.StatusForbidden, "Disabled")
+		return
+	}
+	var reqBody appendMessageReq
+	if err := c.ShouldBindJSON(&reqBody); err != nil || strings.TrimSpace(reqBody.Content) == "" {
+		c.String(http.StatusBadRequest, "Invalid input")
+		return
+	}
+	owner, _ := conversationOwner(c, false)
+	conv, err := conversations.Get(owner, c.Param("id"))
+	if err != nil {
+		conversationError(c, err)
+		return
+	}
+	user := ai.Message{Role: "user", Content: reqBody.Content}
+	msgs := append([]ai.Message{{Role: "system", Content: conv.System}}, conv.Messages...)
+	msgs = ai.Trim(append(msgs, user), contextTokens())
+
+	if c.Query("stream") == "1" {
+		reply, ok := streamReply(c, msgs)
+		if !ok {
+			return
+		}
+		if err := conversations.Append(owner, conv.ID, user, ai.Message{Role: "assistant", Content: reply}); err != nil {
+			log.Printf("conversation storage err: %v", err)
+		}
+		return
+	}
+	answer, _, err := aiProvider(c).Chat(c.Request.Context(), msgs)
+	if err != nil {
+		aiError(c, err)
+		return
+	}
+	reply := ai.Message{Role: "assistant", Content: answer}
+	if err := conversations.Append(owner, conv.ID, user, reply); err != nil {
+		conversationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": reply})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dongzhiwei-git/resume/ai"
+	"github.com/dongzhiwei-git/resume/config"
+	"github.com/dongzhiwei-git/resume/metrics"
+	"github.com/dongzhiwei-git/resume/models"
+	"github.com/dongzhiwei-git/resume/pdf"
+
+	"github.com/gin-gonic/gin"
+)
+
+type coverLetterReq struct {
+	Resume         models.Resume `json:"resume"`
+	JobDescription string        `json:"job_description"`
+	Company        string        `json:"company"`
+	Position       string        `json:"position"`
+}
+
+const coverLetterSchemaPrompt = `{"recipient":"","company":"","position":"","greeting":"","body":"","closing":""}；body 为 3–4 段正文，段落之间用空行分隔；closing 为结束语，如 "此致\n敬礼！"`
+
+// ApiAiCoverLetter drafts a cover letter for one job description from the
+// candidate's resume. Contact details and theme come from the resume, not
+// the model.
+func ApiAiCoverLetter(c *gin.Context) {
+	if !config.AppConfig.EnableAIAssistant {
+		c.String(http.StatusForbidden, "Disabled")
+		return
+	}
+	var reqBody coverLetterReq
+	if err := c.ShouldBindJSON(&reqBody); err != nil || strings.TrimSpace(reqBody.JobDescription) == "" {
+		c.String(http.StatusBadRequest, "Invalid input")
+		return
+	}
+	sys := ai.Message{Role: "system", Content: "你是求职信写作助手。根据候选人的 JSON 简历与职位描述撰写中文求职信：说明应聘动机，结合简历中的真实经历说明与岗位的匹配点，语气专业、简洁，不臆造简历中没有的经历或数据。严格返回 JSON，只返回 JSON。"}
+	resumeJSON, _ := json.Marshal(reqBody.Resume)
+	hint := ""
+	if reqBody.Company != "" {
+		hint += "\n公司：" + reqBody.Company
+	}
+	if reqBody.Position != "" {
+		hint += "\n职位：" + reqBody.Position
+	}
+	user := ai.Message{Role: "user", Content: "简历：" + string(resumeJSON) + "\n职位描述：" + reqBody.JobDescription + hint + "\n输出：" + coverLetterSchemaPrompt + "\n只返回 JSON，不要解释。"}
+
+	var draft models.CoverLetter
+	_, err := aiProvider(c).JSON(c.Request.Context(), []ai.Message{sys, user}, &draft)
+	if err == nil && strings.TrimSpace(draft.Body) == "" {
+		err = ai.ErrBadResponse
+	}
+	if err != nil {
+		aiError(c, err)
+		return
+	}
+	l := models.NewCoverLetter(reqBody.Resume)
+	l.Recipient, l.Greeting, l.Body, l.Closing = draft.Recipient, draft.Greeting, draft.Body, draft.Closing
+	l.Company, l.Position = reqBody.Company, reqBody.Position
+	if l.Company == "" {
+		l.Company = draft.Company
+	}
+	if l.Position == "" {
+		l.Position = draft.Position
+	}
+	l.Date = time.Now().Format("2006年1月2日")
+	c.JSON(http.StatusOK, l)
+}
+
+// bindCoverLetter reads a cover letter from a JSON body and fills in the
+// same theme defaults as the resume handlers. On failure it has already
+// written a 400 response.
+func bindCoverLetter(c *gin.Context) (models.CoverLetter, bool) {
+	var l models.CoverLetter
+	if err := c.ShouldBindJSON(&l); err != nil {
+		c.String(http.StatusBadRequest, "Invalid JSON")
+		return l, false
+	}
+	l.Config = themeDefaults(l.Config)
+	return l, true
+}
+
+func ApiPreviewCoverLetter(c *gin.Context) {
+	l, ok := bindCoverLetter(c)
+	if !ok {
+		return
+	}
+	c.HTML(http.StatusOK, "cover_letter_content.html", gin.H{"Letter": l})
+}
+
+func DownloadCoverLetterPDF(c *gin.Context) {
+	l, ok := bindCoverLetter(c)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	if err := renderPDF("cover_letter", func() error { return pdf.FromEnv().RenderCoverLetter(&buf, l) }); err != nil {
+		pdfError(c, err)
+		return
+	}
+	metrics.IncGenerate()
+	c.Header("Content-Disposition", "attachment; filename=cover_letter.pdf")
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/dongzhiwei-git/resume/ai"
+	"github.com/dongzhiwei-git/resume/config"
+	"github.com/dongzhiwei-git/resume/metrics"
+	"github.com/dongzhiwei-git/resume/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ApiAiGenerateStream is ApiAiGenerateSimple as server-sent events. While
+// the model writes its JSON, every completed top-level field is sent as
+//
+//	event: field  data: {"field":"summary","value":...}
+//
+// and every completed entry of a list, such as one job, as
+//
+//	event: item   data: {"field":"experience","index":0,"value":{...}}
+//
+// Those are previews of the raw reply. Once it is complete it goes through
+// the same checks, repairs and fallback as ApiAiGenerateSimple, and the
+// result is sent as
+//
+//	event: resume data: {"resume":{...},"outcome":"generated"}
+//
+// followed by usage and done as in streamReply.
+func ApiAiGenerateStream(c *gin.Context) {
+	if !config.AppConfig.EnableAIAssistant {
+		c.String(http.StatusForbidden, "Disabled")
+		return
+	}
+	reqBody := simpleGenReq{}
+	if err := c.ShouldBindJSON(&reqBody); err != nil || strings.TrimSpace(reqBody.Input) == "" {
+		c.String(http.StatusBadRequest, "Invalid input")
+		return
+	}
+	s, ok := newSSEStream(c)
+	if !ok {
+		c.String(http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+	ctx := c.Request.Context()
+	p := aiProvider(c)
+	msgs := generateMessages(reqBody.Input)
+
+	var b strings.Builder
+	fields := &ai.JSONFields{
+		Field: func(key string, value json.RawMessage) error {
+			return s.Event("field", gin.H{"field": key, "value": value})
+		},
+		Item: func(key string, index int, value json.RawMessage) error {
+			return s.Event("item", gin.H{"field": key, "index": index, "value": value})
+		},
+	}
+	usage, err := p.Stream(ctx, msgs, func(delta string) error {
+		b.WriteString(delta)
+		return fields.Feed(delta)
+	})
+	var r models.Resume
+	outcome := ai.Fallback
+	if err == nil {
+		var u ai.Usage
+		outcome, u, err = ai.Finish(ctx, p, msgs, b.String(), &r, checkResume(&r), aiRepairs)
+		usage.Add(u)
+	}
+	if ctx.Err() != nil || s.err != nil {
+		// The client is gone.
+		s.Close()
+		return
+	}
+	if errors.Is(err, ai.ErrNotConfigured) {
+		if !s.Close() {
+			aiError(c, err)
+			return
+		}
+		s.Event("error", gin.H{"error": "AI provider not configured"})
+		return
+	}
+	if err != nil {
+		log.Printf("ai generate: %v", err)
+		r, outcome = simpleFromInput(reqBody.Input), ai.Fallback
+	}
+	countAIResume(metrics.EventAIGenerate, outcome)
+	if err := s.Event("resume", gin.H{"resume": withThemeDefaults(r), "outcome": outcome}); err == nil {
+		if err := s.Event("usage", usage); err == nil {
+			s.Event("done", gin.H{})
+		}
+	}
+	s.Close()
+}
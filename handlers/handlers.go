@@ -1,23 +1,32 @@
 package handlers
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"html/template"
 	"io"
+	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/dongzhiwei-git/resume/ai"
 	"github.com/dongzhiwei-git/resume/config"
+	"github.com/dongzhiwei-git/resume/docx"
+	"github.com/dongzhiwei-git/resume/jsonresume"
+	"github.com/dongzhiwei-git/resume/markdown"
 	"github.com/dongzhiwei-git/resume/metrics"
 	"github.com/dongzhiwei-git/resume/models"
+	"github.com/dongzhiwei-git/resume/pdf"
+	"github.com/dongzhiwei-git/resume/plaintext"
+	"github.com/dongzhiwei-git/resume/validation"
 
 	"github.com/gin-gonic/gin"
 )
@@ -42,7 +51,11 @@ func Editor(c *gin.Context) {
 	selectedTemplate := c.Query("template")
 
 	var initialResume models.Resume
-	if selectedTemplate != "" {
+	savedID := ""
+	if saved, ok := loadSavedResume(c.Query("id")); ok {
+		initialResume = saved
+		savedID = c.Query("id")
+	} else if selectedTemplate != "" {
 		initialResume = models.GetDemoResume()
 		initialResume.Config.Template = selectedTemplate
 	} else {
@@ -60,6 +73,7 @@ func Editor(c *gin.Context) {
 	c.HTML(http.StatusOK, "editor.html", gin.H{
 		"title":        "编辑简历",
 		"Resume":       initialResume,
+		"SavedID":      savedID,
 		"Visits":       v,
 		"Generates":    g,
 		"Canonical":    canonical,
@@ -73,6 +87,19 @@ func Preview(c *gin.Context) {
 		return
 	}
 	resume := parseResumeFromForm(c)
+	// A browser navigation, so problems are shown as a page rather than
+	// as validResume's JSON.
+	if errs := validation.Validate(resume); len(errs) > 0 {
+		v, g := metrics.Snapshot()
+		c.HTML(http.StatusUnprocessableEntity, "invalid.html", gin.H{
+			"title":        "简历内容需要修改",
+			"Errors":       errs,
+			"Visits":       v,
+			"Generates":    g,
+			"ServerConfig": config.AppConfig,
+		})
+		return
+	}
 
 	if resume.Config.Color == "" {
 		resume.Config.Color = "#333333"
@@ -81,6 +108,7 @@ func Preview(c *gin.Context) {
 		resume.Config.Template = "classic"
 	}
 
+	metrics.Inc(metrics.EventPreview)
 	v, g := metrics.Snapshot()
 	scheme := c.Request.Header.Get("X-Forwarded-Proto")
 	if scheme == "" {
@@ -111,7 +139,9 @@ func ApiPreview(c *gin.Context) {
 	}
 
 	resume := parseResumeFromForm(c)
-	fmt.Printf("Received Resume: %+v\n", resume)
+	if !validDraft(c, resume) {
+		return
+	}
 
 	if resume.Config.Color == "" {
 		resume.Config.Color = "#333333"
@@ -132,7 +162,54 @@ func GenerateEvent(c *gin.Context) {
 
 func SnapshotAPI(c *gin.Context) {
 	v, g := metrics.Snapshot()
-	c.JSON(http.StatusOK, gin.H{"visits": v, "generates": g})
+	uv, err := metrics.UniqueVisitorsToday()
+	if err != nil {
+		log.Printf("metrics unique visitors err: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"visits": v, "generates": g, "unique_visitors_today": uv})
+}
+
+// MetricsSeries returns daily counts for ?from=&to= (YYYY-MM-DD, inclusive,
+// default the last 30 days) and ?event= (default every event type).
+func MetricsSeries(c *gin.Context) {
+	to := c.DefaultQuery("to", time.Now().Format("2006-01-02"))
+	end, err := time.ParseInLocation("2006-01-02", to, time.Local)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid date")
+		return
+	}
+	from := c.DefaultQuery("from", end.AddDate(0, 0, -29).Format("2006-01-02"))
+	start, err := time.ParseInLocation("2006-01-02", from, time.Local)
+	if err != nil || start.After(end) {
+		c.String(http.StatusBadRequest, "Invalid date")
+		return
+	}
+	if end.Sub(start) > 366*24*time.Hour {
+		c.String(http.StatusBadRequest, "Range too long")
+		return
+	}
+	events := metrics.SeriesEvents
+	if e := c.Query("event"); e != "" {
+		if !metrics.KnownEvent(e) {
+			c.String(http.StatusBadRequest, "Unknown event")
+			return
+		}
+		events = []string{e}
+	}
+	series, err := metrics.Series(from, to, events)
+	if err != nil {
+		log.Printf("metrics series err: %v", err)
+		c.String(http.StatusInternalServerError, "Failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "series": series})
+}
+
+// PrometheusMetrics serves the process metrics in the Prometheus text format.
+func PrometheusMetrics(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	metrics.WritePrometheus(c.Writer)
 }
 
 func Health(c *gin.Context) {
@@ -160,13 +237,28 @@ func AiPage(c *gin.Context) {
 	})
 }
 
-type chatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+type aiAskReq struct {
+	Messages []ai.Message `json:"messages"`
 }
 
-type aiAskReq struct {
-	Messages []chatMessage `json:"messages"`
+// aiError maps a provider failure to an HTTP response.
+func aiError(c *gin.Context, err error) {
+	c.String(aiErrorStatus(err))
+}
+
+// aiErrorStatus logs err unless it is a configuration problem and maps it to
+// the status and message shown to clients.
+func aiErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, ai.ErrNotConfigured):
+		return http.StatusBadRequest, "AI provider not configured"
+	case errors.Is(err, ai.ErrBadResponse):
+		log.Printf("ai: %v", err)
+		return http.StatusBadGateway, "Bad AI response"
+	default:
+		log.Printf("ai: %v", err)
+		return http.StatusBadGateway, "AI unavailable"
+	}
 }
 
 func ApiAiAsk(c *gin.Context) {
@@ -174,113 +266,121 @@ func ApiAiAsk(c *gin.Context) {
 		c.String(http.StatusForbidden, "Disabled")
 		return
 	}
-	apiURL := os.Getenv("DEEPSEEK_API_URL")
-	if apiURL == "" {
-		apiURL = "https://api.deepseek.com/v1/chat/completions"
-	}
-	apiKey := os.Getenv("DEEPSEEK_API_KEY")
-	if apiKey == "" {
-		c.String(http.StatusBadRequest, "Missing API key")
-		return
-	}
-	model := os.Getenv("DEEPSEEK_MODEL")
-	if model == "" {
-		model = "deepseek-chat"
-	}
 	body := aiAskReq{}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.String(http.StatusBadRequest, "Invalid JSON")
 		return
 	}
-	promptBytes, _ := os.ReadFile("docs/prompts/deepseek_resume_prompt.md")
-	sys := chatMessage{Role: "system", Content: string(promptBytes)}
-	msgs := append([]chatMessage{sys}, body.Messages...)
-	payload := map[string]any{"model": model, "messages": msgs}
-	b, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", apiURL, bytes.NewReader(b))
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	sys := ai.Message{Role: "system", Content: chatSystemPrompt()}
+	msgs := append([]ai.Message{sys}, body.Messages...)
+	answer, _, err := aiProvider(c).Chat(c.Request.Context(), msgs)
 	if err != nil {
-		c.String(http.StatusBadGateway, "AI unavailable")
-		return
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		c.String(http.StatusBadGateway, "AI error")
+		aiError(c, err)
 		return
 	}
-	var out struct {
-		Choices []struct {
-			Message chatMessage `json:"message"`
-		} `json:"choices"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		c.String(http.StatusBadGateway, "Bad AI response")
-		return
-	}
-	if len(out.Choices) == 0 {
-		c.String(http.StatusBadGateway, "No answer")
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"message": out.Choices[0].Message})
+	c.JSON(http.StatusOK, gin.H{"message": ai.Message{Role: "assistant", Content: answer}})
+}
+
+// chatSystemPrompt is the system prompt of the assistant chat.
+func chatSystemPrompt() string {
+	promptBytes, _ := os.ReadFile("docs/prompts/deepseek_resume_prompt.md")
+	return string(promptBytes)
 }
 
+// ApiAiStream streams the reply as the server's own events (see sseStream),
+// whatever the backend.
 func ApiAiStream(c *gin.Context) {
 	if !config.AppConfig.EnableAIAssistant {
 		c.String(http.StatusForbidden, "Disabled")
 		return
 	}
-	apiURL := os.Getenv("DEEPSEEK_API_URL")
-	if apiURL == "" {
-		apiURL = "https://api.deepseek.com/v1/chat/completions"
-	}
-	apiKey := os.Getenv("DEEPSEEK_API_KEY")
-	if apiKey == "" {
-		c.String(http.StatusBadRequest, "Missing API key")
-		return
-	}
-	model := os.Getenv("DEEPSEEK_MODEL")
-	if model == "" {
-		model = "deepseek-chat"
-	}
 	body := aiAskReq{}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.String(http.StatusBadRequest, "Invalid JSON")
 		return
 	}
-	promptBytes, _ := os.ReadFile("docs/prompts/deepseek_resume_prompt.md")
-	sys := chatMessage{Role: "system", Content: string(promptBytes)}
-	msgs := append([]chatMessage{sys}, body.Messages...)
-	payload := map[string]any{"model": model, "messages": msgs, "stream": true}
-	b, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", apiURL, bytes.NewReader(b))
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		c.String(http.StatusBadGateway, "AI unavailable")
-		return
-	}
-	defer resp.Body.Close()
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	fl, ok := c.Writer.(http.Flusher)
+	sys := ai.Message{Role: "system", Content: chatSystemPrompt()}
+	streamReply(c, append([]ai.Message{sys}, body.Messages...))
+}
+
+// streamReply sends the reply to msgs as the events of sseStream and
+// returns its full text. ok is false when the reply did not complete; the
+// response has been written either way. A client that goes away cancels
+// the request context and with it the upstream call.
+func streamReply(c *gin.Context, msgs []ai.Message) (reply string, ok bool) {
+	s, ok := newSSEStream(c)
 	if !ok {
 		c.String(http.StatusInternalServerError, "Streaming unsupported")
-		return
+		return "", false
 	}
-	r := bufio.NewReader(resp.Body)
-	for {
-		line, err := r.ReadBytes('\n')
-		if len(line) > 0 {
-			c.Writer.Write(line)
-			fl.Flush()
+	ctx := c.Request.Context()
+	var b strings.Builder
+	usage, err := aiProvider(c).Stream(ctx, msgs, func(delta string) error {
+		b.WriteString(delta)
+		return s.Event("delta", gin.H{"content": delta})
+	})
+	if err == nil {
+		if err = s.Event("usage", usage); err == nil {
+			err = s.Event("done", gin.H{})
 		}
-		if err != nil {
-			break
+	}
+	started := s.Close()
+	switch {
+	case err == nil:
+		return b.String(), true
+	case ctx.Err() != nil || s.err != nil:
+		// The client is gone; there is no one to tell.
+		return "", false
+	case !started:
+		aiError(c, err)
+		return "", false
+	}
+	_, msg := aiErrorStatus(err)
+	s.Event("error", gin.H{"error": msg})
+	return "", false
+}
+
+// resumeSchemaPrompt tells the model the exact JSON shape of models.Resume.
+const resumeSchemaPrompt = `仅输出一个严格的 JSON 对象，键名与结构如下（全部小写）：
+{"name":"","email":"","phone":"","summary":"","avatar":"","config":{"template":"classic","color":"#333333","font":"","font_size":"","paper_size":"a4","section_order":[]},"experience":[{"title":"","company":"","date":"YYYY-MM","description":""}],"education":[{"degree":"","school":"","date":"YYYY-MM"}],"skills":[{"category":"","items":[{"name":"","level":""}]}],"projects":[{"name":"","role":"","date":"YYYY-MM","url":"","description":""}],"certifications":[{"name":"","issuer":"","date":"YYYY-MM"}],"awards":[{"title":"","issuer":"","date":"YYYY-MM","description":""}],"custom_sections":[{"title":"","items":[{"heading":"","subtitle":"","date":"","body":""}]}]}
+skills 按类别分组（如 编程语言、框架与工具），level 为可选的熟练度（精通/熟练/了解），未知留空。
+custom_sections 用于论文、专利、志愿经历等无法归入上述栏目的内容；section_order 可选，取值为 summary/skills/experience/projects/education/certifications/awards 与 custom.0、custom.1 等，留空使用默认顺序。`
+
+// aiRepairs is how many times a resume reply that fails the schema or
+// validation is sent back to the model before falling back.
+const aiRepairs = 2
+
+// structuredResume runs msgs through the structured-output layer, checking
+// the reply with checkResume.
+func structuredResume(ctx context.Context, p ai.Provider, msgs []ai.Message) (models.Resume, string, error) {
+	var r models.Resume
+	outcome, _, err := ai.Structured(ctx, p, msgs, &r, checkResume(&r), aiRepairs)
+	return r, outcome, err
+}
+
+// checkResume checks a model's resume with the preview's draft rules:
+// formats and dates, but no required fields, since the prompts tell the
+// model not to invent names, companies or schools the user left out.
+func checkResume(r *models.Resume) func() error {
+	return func() error {
+		if errs := validation.ValidateDraft(*r); errs != nil {
+			return errs
 		}
+		return nil
+	}
+}
+
+// setAIOutcome reports how a structured reply was obtained: ai.Generated,
+// ai.Repaired or ai.Fallback.
+func setAIOutcome(c *gin.Context, outcome string) {
+	c.Header("X-AI-Outcome", outcome)
+}
+
+// countAIResume counts event for a resume the model produced, but not for a
+// fallback.
+func countAIResume(event, outcome string) {
+	if outcome != ai.Fallback {
+		metrics.Inc(event)
 	}
 }
 
@@ -315,86 +415,57 @@ func simpleFromInput(s string) models.Resume {
 	return r
 }
 
+// generateMessages asks for a resume built from a one-line description.
+func generateMessages(input string) []ai.Message {
+	sys := ai.Message{Role: "system", Content: "你是简历生成助手。请在不臆造个人信息的前提下，尽量饱满地填充内容：总结要简洁全面，经验描述采用 3–5 条要点以中文分号分隔，包含动作、方法、数据结果。严格生成符合站点 schema 的 JSON，键名小写，只返回 JSON。"}
+	user := ai.Message{Role: "user", Content: "输入：" + input + "\n要求：" + resumeSchemaPrompt + "\n只返回 JSON，不要任何解释。未知值留空字符串或空数组。"}
+	return []ai.Message{sys, user}
+}
+
 func ApiAiGenerateSimple(c *gin.Context) {
 	if !config.AppConfig.EnableAIAssistant {
 		c.String(http.StatusForbidden, "Disabled")
 		return
 	}
-	apiURL := os.Getenv("DEEPSEEK_API_URL")
-	if apiURL == "" {
-		apiURL = "https://api.deepseek.com/v1/chat/completions"
-	}
-	apiKey := os.Getenv("DEEPSEEK_API_KEY")
-	if apiKey == "" {
-		c.String(http.StatusBadRequest, "Missing API key")
-		return
-	}
-	model := os.Getenv("DEEPSEEK_MODEL")
-	if model == "" {
-		model = "deepseek-chat"
-	}
-
 	reqBody := simpleGenReq{}
 	if err := c.ShouldBindJSON(&reqBody); err != nil || strings.TrimSpace(reqBody.Input) == "" {
 		c.String(http.StatusBadRequest, "Invalid input")
 		return
 	}
-	schema := `仅输出一个严格的 JSON 对象，键名与结构如下（全部小写）：
-{"name":"","email":"","phone":"","summary":"","avatar":"","config":{"template":"classic","color":"#333333","font":"","font_size":"","paper_size":"a4"},"experience":[{"title":"","company":"","date":"YYYY-MM","description":""}],"education":[{"degree":"","school":"","date":"YYYY-MM"}]}`
-	sys := chatMessage{Role: "system", Content: "你是简历生成助手。请在不臆造个人信息的前提下，尽量饱满地填充内容：总结要简洁全面，经验描述采用 3–5 条要点以中文分号分隔，包含动作、方法、数据结果。严格生成符合站点 schema 的 JSON，键名小写，只返回 JSON。"}
-	user := chatMessage{Role: "user", Content: "输入：" + reqBody.Input + "\n要求：" + schema + "\n只返回 JSON，不要任何解释。未知值留空字符串或空数组。"}
-	payload := map[string]any{"model": model, "messages": []chatMessage{sys, user}}
-	b, _ := json.Marshal(payload)
-	httpReq, _ := http.NewRequest("POST", apiURL, bytes.NewReader(b))
-	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
-	httpReq.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(httpReq)
+	r, outcome, err := structuredResume(c.Request.Context(), aiProvider(c), generateMessages(reqBody.Input))
 	if err != nil {
-		r := simpleFromInput(reqBody.Input)
-		c.JSON(http.StatusOK, r)
-		return
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		r := simpleFromInput(reqBody.Input)
-		c.JSON(http.StatusOK, r)
-		return
-	}
-	var out struct {
-		Choices []struct {
-			Message chatMessage `json:"message"`
-		} `json:"choices"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		r := simpleFromInput(reqBody.Input)
-		c.JSON(http.StatusOK, r)
-		return
-	}
-	if len(out.Choices) == 0 {
-		r := simpleFromInput(reqBody.Input)
-		c.JSON(http.StatusOK, r)
-		return
-	}
-	var r models.Resume
-	content := out.Choices[0].Message.Content
-	if err := json.Unmarshal([]byte(content), &r); err != nil {
-		i := strings.Index(content, "{")
-		j := strings.LastIndex(content, "}")
-		if i >= 0 && j > i {
-			if err2 := json.Unmarshal([]byte(content[i:j+1]), &r); err2 != nil {
-				r = simpleFromInput(reqBody.Input)
-			}
-		} else {
-			r = simpleFromInput(reqBody.Input)
+		if errors.Is(err, ai.ErrNotConfigured) {
+			aiError(c, err)
+			return
 		}
+		log.Printf("ai generate: %v", err)
+		r, outcome = simpleFromInput(reqBody.Input), ai.Fallback
 	}
-	if r.Config.Color == "" {
-		r.Config.Color = "#333333"
+	setAIOutcome(c, outcome)
+	countAIResume(metrics.EventAIGenerate, outcome)
+	c.JSON(http.StatusOK, withThemeDefaults(r))
+}
+
+// withThemeDefaults fills in the colour, template and paper size of a
+// generated or revised resume.
+func withThemeDefaults(r models.Resume) models.Resume {
+	r.Config = themeDefaults(r.Config)
+	return r
+}
+
+// themeDefaults fills in the colour, template and paper size left empty in
+// cfg, for resumes and cover letters alike.
+func themeDefaults(cfg models.ThemeConfig) models.ThemeConfig {
+	if cfg.Color == "" {
+		cfg.Color = "#333333"
 	}
-	if r.Config.Template == "" {
-		r.Config.Template = "classic"
+	if cfg.Template == "" {
+		cfg.Template = "classic"
 	}
-	c.JSON(http.StatusOK, r)
+	if cfg.PaperSize == "" {
+		cfg.PaperSize = "a4"
+	}
+	return cfg
 }
 
 type reviseReq struct {
@@ -402,88 +473,41 @@ type reviseReq struct {
 	Resume      models.Resume `json:"resume"`
 }
 
+// reviseResume asks the model to rewrite base according to instruction and
+// returns the outcome of the structured request. On failure it returns base
+// unchanged, ai.Fallback and the error, so callers can keep the original.
+func reviseResume(ctx context.Context, p ai.Provider, base models.Resume, instruction string) (models.Resume, string, error) {
+	sys := ai.Message{Role: "system", Content: "你是简历生成助手。根据现有 JSON 简历与用户修改要求，更新并优化简历：保持事实，不臆造；经验描述以 3–5 条要点的中文分号分隔补充动作、方法、数据。严格返回符合站点 schema 的 JSON，键名小写，只返回 JSON。"}
+	oldJSON, _ := json.Marshal(base)
+	user := ai.Message{Role: "user", Content: "现有简历：" + string(oldJSON) + "\n修改要求：" + instruction + "\n输出：" + resumeSchemaPrompt + "\n只返回 JSON，不要解释。"}
+	r, outcome, err := structuredResume(ctx, p, []ai.Message{sys, user})
+	if err != nil {
+		return base, ai.Fallback, err
+	}
+	return r, outcome, nil
+}
+
 func ApiAiRevise(c *gin.Context) {
 	if !config.AppConfig.EnableAIAssistant {
 		c.String(http.StatusForbidden, "Disabled")
 		return
 	}
-	apiURL := os.Getenv("DEEPSEEK_API_URL")
-	if apiURL == "" {
-		apiURL = "https://api.deepseek.com/v1/chat/completions"
-	}
-	apiKey := os.Getenv("DEEPSEEK_API_KEY")
-	if apiKey == "" {
-		c.String(http.StatusBadRequest, "Missing API key")
-		return
-	}
-	model := os.Getenv("DEEPSEEK_MODEL")
-	if model == "" {
-		model = "deepseek-chat"
-	}
-
 	var reqBody reviseReq
 	if err := c.ShouldBindJSON(&reqBody); err != nil || strings.TrimSpace(reqBody.Instruction) == "" {
 		c.String(http.StatusBadRequest, "Invalid input")
 		return
 	}
-	schema := `仅输出一个严格的 JSON 对象，键名与结构如下（全部小写）：
-{"name":"","email":"","phone":"","summary":"","avatar":"","config":{"template":"classic","color":"#333333","font":"","font_size":"","paper_size":"a4"},"experience":[{"title":"","company":"","date":"YYYY-MM","description":""}],"education":[{"degree":"","school":"","date":"YYYY-MM"}]}`
-	sys := chatMessage{Role: "system", Content: "你是简历生成助手。根据现有 JSON 简历与用户修改要求，更新并优化简历：保持事实，不臆造；经验描述以 3–5 条要点的中文分号分隔补充动作、方法、数据。严格返回符合站点 schema 的 JSON，键名小写，只返回 JSON。"}
-	oldJSON, _ := json.Marshal(reqBody.Resume)
-	user := chatMessage{Role: "user", Content: "现有简历：" + string(oldJSON) + "\n修改要求：" + reqBody.Instruction + "\n输出：" + schema + "\n只返回 JSON，不要解释。"}
-	payload := map[string]any{"model": model, "messages": []chatMessage{sys, user}}
-	b, _ := json.Marshal(payload)
-	httpReq, _ := http.NewRequest("POST", apiURL, bytes.NewReader(b))
-	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
-	httpReq.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(httpReq)
+	r, outcome, err := reviseResume(c.Request.Context(), aiProvider(c), reqBody.Resume, reqBody.Instruction)
 	if err != nil {
-		r := reqBody.Resume
-		c.JSON(http.StatusOK, r)
-		return
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		r := reqBody.Resume
-		c.JSON(http.StatusOK, r)
-		return
-	}
-	var out struct {
-		Choices []struct {
-			Message chatMessage `json:"message"`
-		} `json:"choices"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		c.JSON(http.StatusOK, reqBody.Resume)
-		return
-	}
-	if len(out.Choices) == 0 {
-		c.JSON(http.StatusOK, reqBody.Resume)
-		return
-	}
-	content := out.Choices[0].Message.Content
-	var r models.Resume
-	if err := json.Unmarshal([]byte(content), &r); err != nil {
-		i := strings.Index(content, "{")
-		j := strings.LastIndex(content, "}")
-		if i >= 0 && j > i {
-			if err2 := json.Unmarshal([]byte(content[i:j+1]), &r); err2 != nil {
-				r = reqBody.Resume
-			}
-		} else {
-			r = reqBody.Resume
+		if errors.Is(err, ai.ErrNotConfigured) {
+			aiError(c, err)
+			return
 		}
+		log.Printf("ai revise: %v", err)
 	}
-	if r.Config.Color == "" {
-		r.Config.Color = "#333333"
-	}
-	if r.Config.Template == "" {
-		r.Config.Template = "classic"
-	}
-	if r.Config.PaperSize == "" {
-		r.Config.PaperSize = "a4"
-	}
-	c.JSON(http.StatusOK, r)
+	setAIOutcome(c, outcome)
+	countAIResume(metrics.EventAIRevise, outcome)
+	c.JSON(http.StatusOK, withThemeDefaults(r))
 }
 
 func ApiPreviewJSON(c *gin.Context) {
@@ -492,6 +516,9 @@ func ApiPreviewJSON(c *gin.Context) {
 		c.String(http.StatusBadRequest, "Invalid JSON")
 		return
 	}
+	if !validDraft(c, resume) {
+		return
+	}
 	if resume.Config.Color == "" {
 		resume.Config.Color = "#333333"
 	}
@@ -501,78 +528,174 @@ func ApiPreviewJSON(c *gin.Context) {
 	c.HTML(http.StatusOK, "resume_content.html", gin.H{"Resume": resume})
 }
 
-func DownloadPDF(c *gin.Context) {
-	apiURL := os.Getenv("PDF_API_URL")
-	apiKey := os.Getenv("PDF_API_KEY")
-	if apiURL == "" || apiKey == "" {
-		c.String(http.StatusBadRequest, "PDF service not configured")
-		return
-	}
-
+// bindResume reads a resume from either a JSON body or the editor's multipart
+// form. On failure it has already written a 400 response.
+func bindResume(c *gin.Context) (models.Resume, bool) {
 	var resume models.Resume
 	ct := c.GetHeader("Content-Type")
 	if strings.HasPrefix(ct, "application/json") {
 		if err := c.ShouldBindJSON(&resume); err != nil {
 			c.String(http.StatusBadRequest, "Invalid JSON")
-			return
+			return resume, false
 		}
 	} else {
 		if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
 			c.String(http.StatusBadRequest, "Invalid form")
-			return
+			return resume, false
 		}
 		resume = parseResumeFromForm(c)
 	}
+	return resume, true
+}
 
-	if resume.Config.Color == "" {
-		resume.Config.Color = "#333333"
+// validResume writes a 422 with field-level errors when r fails validation.
+func validResume(c *gin.Context, r models.Resume) bool {
+	if errs := validation.Validate(r); len(errs) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid resume", "errors": errs})
+		return false
 	}
-	if resume.Config.Template == "" {
-		resume.Config.Template = "classic"
+	return true
+}
+
+// validDraft is validResume for live previews, which render resumes that
+// are still incomplete: missing required fields are not reported.
+func validDraft(c *gin.Context, r models.Resume) bool {
+	if errs := validation.ValidateDraft(r); len(errs) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid resume", "errors": errs})
+		return false
+	}
+	return true
+}
+
+// ApiValidate reports field-level problems without rendering anything.
+func ApiValidate(c *gin.Context) {
+	resume, ok := bindResume(c)
+	if !ok {
+		return
 	}
-	if resume.Config.PaperSize == "" {
-		resume.Config.PaperSize = "a4"
+	errs := validation.Validate(resume)
+	if errs == nil {
+		errs = validation.Errors{}
 	}
+	c.JSON(http.StatusOK, gin.H{"valid": len(errs) == 0, "errors": errs})
+}
 
-	tpl, err := template.ParseFiles("templates/resume_content.html")
-	if err != nil {
-		c.String(http.StatusInternalServerError, "Template error")
+// renderPDF runs render, recording its duration and result as document.
+func renderPDF(document string, render func() error) error {
+	start := time.Now()
+	err := render()
+	metrics.ObservePDFRender(document, time.Since(start), err)
+	return err
+}
+
+func pdfError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, pdf.ErrNotConfigured):
+		c.String(http.StatusBadRequest, "PDF service not configured")
+	case errors.Is(err, pdf.ErrUnavailable):
+		c.String(http.StatusBadGateway, "PDF service unavailable")
+	default:
+		log.Printf("pdf render err: %v", err)
+		c.String(http.StatusBadGateway, "PDF generation failed")
+	}
+}
+
+func DownloadPDF(c *gin.Context) {
+	resume, ok := bindResume(c)
+	if !ok || !validResume(c, resume) {
 		return
 	}
+
+	resume = withThemeDefaults(resume)
+
 	var buf bytes.Buffer
-	if err := tpl.Execute(&buf, gin.H{"Resume": resume}); err != nil {
-		c.String(http.StatusInternalServerError, "Render error")
-		return
-	}
-	cssBytes, _ := os.ReadFile("static/css/style.css")
-	html := "<!DOCTYPE html><html><head><meta charset=\"utf-8\"><style>" + string(cssBytes) + "</style></head><body>" + buf.String() + "</body></html>"
-
-	payload := map[string]any{
-		"html": html,
-		"options": map[string]any{
-			"printBackground": true,
-			"format":          strings.ToUpper(resume.Config.PaperSize),
-			"margin":          map[string]string{"top": "0.5in", "bottom": "0.5in", "left": "0.5in", "right": "0.5in"},
-		},
-	}
-	body, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", apiURL, bytes.NewReader(body))
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		c.String(http.StatusBadGateway, "PDF service unavailable")
+	if err := renderPDF("resume", func() error { return pdf.FromEnv().Render(&buf, resume) }); err != nil {
+		pdfError(c, err)
 		return
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		c.String(http.StatusBadGateway, "PDF generation failed")
+	metrics.IncGenerate()
+	c.Header("Content-Disposition", "attachment; filename=resume.pdf")
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+func DownloadDOCX(c *gin.Context) {
+	resume, ok := bindResume(c)
+	if !ok || !validResume(c, resume) {
+		return
+	}
+	var buf bytes.Buffer
+	if err := docx.Write(&buf, resume); err != nil {
+		log.Printf("docx render err: %v", err)
+		c.String(http.StatusInternalServerError, "DOCX generation failed")
 		return
 	}
 	metrics.IncGenerate()
-	c.Header("Content-Type", "application/pdf")
-	c.Header("Content-Disposition", "attachment; filename=resume.pdf")
-	io.Copy(c.Writer, resp.Body)
+	c.Header("Content-Disposition", "attachment; filename=resume.docx")
+	c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.wordprocessingml.document", buf.Bytes())
+}
+
+func DownloadMarkdown(c *gin.Context) {
+	resume, ok := bindResume(c)
+	if !ok || !validResume(c, resume) {
+		return
+	}
+	var buf bytes.Buffer
+	if err := markdown.Write(&buf, resume); err != nil {
+		c.String(http.StatusInternalServerError, "Markdown generation failed")
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=resume.md")
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", buf.Bytes())
+}
+
+func DownloadTXT(c *gin.Context) {
+	resume, ok := bindResume(c)
+	if !ok || !validResume(c, resume) {
+		return
+	}
+	var buf bytes.Buffer
+	if err := plaintext.Write(&buf, resume); err != nil {
+		c.String(http.StatusInternalServerError, "Text generation failed")
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=resume.txt")
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", buf.Bytes())
+}
+
+// ApiConvertJSONResume converts between this site's resume JSON and the JSON
+// Resume schema. The direction is taken from ?to=jsonresume|resume, or
+// detected from the body when omitted.
+func ApiConvertJSONResume(c *gin.Context) {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid body")
+		return
+	}
+	to := c.Query("to")
+	if to == "" {
+		to = "jsonresume"
+		if jsonresume.Detect(data) {
+			to = "resume"
+		}
+	}
+	switch to {
+	case "jsonresume":
+		var resume models.Resume
+		if err := json.Unmarshal(data, &resume); err != nil {
+			c.String(http.StatusBadRequest, "Invalid JSON")
+			return
+		}
+		c.JSON(http.StatusOK, jsonresume.FromResume(resume))
+	case "resume":
+		var jr jsonresume.Resume
+		if err := json.Unmarshal(data, &jr); err != nil {
+			c.String(http.StatusBadRequest, "Invalid JSON")
+			return
+		}
+		c.JSON(http.StatusOK, jsonresume.ToResume(jr))
+	default:
+		c.String(http.StatusBadRequest, "Unknown target format")
+	}
 }
 
 func Robots(c *gin.Context) {
@@ -620,6 +743,7 @@ func Import(c *gin.Context) {
 		c.String(http.StatusBadRequest, "Upload failed")
 		return
 	}
+	metrics.AddUploadBytes("import", file.Size)
 
 	// Read file content
 	f, err := file.Open()
@@ -629,12 +753,31 @@ func Import(c *gin.Context) {
 	}
 	defer f.Close()
 
-	// Decode JSON
+	data, err := io.ReadAll(f)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Read file failed")
+		return
+	}
+
 	var resume models.Resume
-	if err := json.NewDecoder(f).Decode(&resume); err != nil {
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if ext == ".md" || ext == ".markdown" {
+		if resume, err = markdown.Parse(bytes.NewReader(data)); err != nil {
+			c.String(http.StatusBadRequest, "Invalid Markdown: %v", err)
+			return
+		}
+	} else if jsonresume.Detect(data) {
+		var jr jsonresume.Resume
+		if err := json.Unmarshal(data, &jr); err != nil {
+			c.String(http.StatusBadRequest, "Invalid JSON Resume: %v", err)
+			return
+		}
+		resume = jsonresume.ToResume(jr)
+	} else if err := json.Unmarshal(data, &resume); err != nil {
 		c.String(http.StatusBadRequest, "Invalid JSON: %v", err)
 		return
 	}
+	metrics.Inc(metrics.EventImport)
 
 	// Render editor with data
 	c.HTML(http.StatusOK, "editor.html", gin.H{
@@ -655,6 +798,7 @@ func parseResumeFromForm(c *gin.Context) models.Resume {
 	r.Config.Font = c.PostForm("config.font")
 	r.Config.FontSize = c.PostForm("config.font_size")
 	r.Config.PaperSize = c.PostForm("config.paper_size")
+	r.Config.SectionOrder = models.ParseSectionOrder(c.PostForm("config.section_order"))
 
 	// Handle File Upload
 	file, err := c.FormFile("avatar")
@@ -663,6 +807,7 @@ func parseResumeFromForm(c *gin.Context) models.Resume {
 		dst := "static/uploads/" + file.Filename
 		// Ensure unique name to prevent collisions in real app, but for now:
 		if err := c.SaveUploadedFile(file, dst); err == nil {
+			metrics.AddUploadBytes("avatar", file.Size)
 			r.Avatar = "/" + dst
 		} else {
 			fmt.Println("Save file error:", err)
@@ -672,12 +817,26 @@ func parseResumeFromForm(c *gin.Context) models.Resume {
 		r.Avatar = c.PostForm("avatar_existing")
 	}
 
-	// Parse Experience and Education using regex
+	// Parse Experience, Education, Skills, Projects, Certifications and Awards using regex
 	expMap := map[int]*models.Exp{}
 	eduMap := map[int]*models.Edu{}
+	projMap := map[int]*models.Project{}
+	certMap := map[int]*models.Certification{}
+	awardMap := map[int]*models.Award{}
+	skillMap := map[int]*models.SkillGroup{}
+	skillItemMap := map[int]map[int]*models.SkillItem{}
+	customMap := map[int]*models.CustomSection{}
+	customItemMap := map[int]map[int]*models.CustomItem{}
 
 	expRe := regexp.MustCompile(`^experience\[(\d+)\]\.(title|company|date|description)$`)
 	eduRe := regexp.MustCompile(`^education\[(\d+)\]\.(degree|school|date)$`)
+	projRe := regexp.MustCompile(`^projects\[(\d+)\]\.(name|role|date|url|description)$`)
+	certRe := regexp.MustCompile(`^certifications\[(\d+)\]\.(name|issuer|date)$`)
+	awardRe := regexp.MustCompile(`^awards\[(\d+)\]\.(title|issuer|date|description)$`)
+	skillRe := regexp.MustCompile(`^skills\[(\d+)\]\.(category|items)$`)
+	skillItemRe := regexp.MustCompile(`^skills\[(\d+)\]\.items\[(\d+)\]\.(name|level)$`)
+	customRe := regexp.MustCompile(`^custom_sections\[(\d+)\]\.(title|items)$`)
+	customItemRe := regexp.MustCompile(`^custom_sections\[(\d+)\]\.items\[(\d+)\]\.(heading|subtitle|date|body)$`)
 
 	for key, vals := range c.Request.PostForm {
 		if len(vals) == 0 {
@@ -719,6 +878,129 @@ func parseResumeFromForm(c *gin.Context) models.Resume {
 			case "date":
 				e.Date = val
 			}
+		} else if m := projRe.FindStringSubmatch(key); len(m) == 3 {
+			idx, _ := strconv.Atoi(m[1])
+			p := projMap[idx]
+			if p == nil {
+				p = &models.Project{}
+				projMap[idx] = p
+			}
+			switch m[2] {
+			case "name":
+				p.Name = val
+			case "role":
+				p.Role = val
+			case "date":
+				p.Date = val
+			case "url":
+				p.URL = val
+			case "description":
+				p.Description = val
+			}
+		} else if m := certRe.FindStringSubmatch(key); len(m) == 3 {
+			idx, _ := strconv.Atoi(m[1])
+			ct := certMap[idx]
+			if ct == nil {
+				ct = &models.Certification{}
+				certMap[idx] = ct
+			}
+			switch m[2] {
+			case "name":
+				ct.Name = val
+			case "issuer":
+				ct.Issuer = val
+			case "date":
+				ct.Date = val
+			}
+		} else if m := awardRe.FindStringSubmatch(key); len(m) == 3 {
+			idx, _ := strconv.Atoi(m[1])
+			a := awardMap[idx]
+			if a == nil {
+				a = &models.Award{}
+				awardMap[idx] = a
+			}
+			switch m[2] {
+			case "title":
+				a.Title = val
+			case "issuer":
+				a.Issuer = val
+			case "date":
+				a.Date = val
+			case "description":
+				a.Description = val
+			}
+		} else if m := skillRe.FindStringSubmatch(key); len(m) == 3 {
+			idx, _ := strconv.Atoi(m[1])
+			g := skillMap[idx]
+			if g == nil {
+				g = &models.SkillGroup{}
+				skillMap[idx] = g
+			}
+			switch m[2] {
+			case "category":
+				g.Category = val
+			case "items":
+				// Single-line shorthand used by the editor: "Go:精通, MySQL".
+				g.Items = append(g.Items, models.ParseSkillItems(val)...)
+			}
+		} else if m := skillItemRe.FindStringSubmatch(key); len(m) == 4 {
+			idx, _ := strconv.Atoi(m[1])
+			itemIdx, _ := strconv.Atoi(m[2])
+			if skillMap[idx] == nil {
+				skillMap[idx] = &models.SkillGroup{}
+			}
+			if skillItemMap[idx] == nil {
+				skillItemMap[idx] = map[int]*models.SkillItem{}
+			}
+			it := skillItemMap[idx][itemIdx]
+			if it == nil {
+				it = &models.SkillItem{}
+				skillItemMap[idx][itemIdx] = it
+			}
+			switch m[3] {
+			case "name":
+				it.Name = val
+			case "level":
+				it.Level = val
+			}
+		} else if m := customRe.FindStringSubmatch(key); len(m) == 3 {
+			idx, _ := strconv.Atoi(m[1])
+			cs := customMap[idx]
+			if cs == nil {
+				cs = &models.CustomSection{}
+				customMap[idx] = cs
+			}
+			switch m[2] {
+			case "title":
+				cs.Title = val
+			case "items":
+				// Textarea shorthand used by the editor, see CustomSection.ItemsText.
+				cs.Items = append(cs.Items, models.ParseCustomItems(val)...)
+			}
+		} else if m := customItemRe.FindStringSubmatch(key); len(m) == 4 {
+			idx, _ := strconv.Atoi(m[1])
+			itemIdx, _ := strconv.Atoi(m[2])
+			if customMap[idx] == nil {
+				customMap[idx] = &models.CustomSection{}
+			}
+			if customItemMap[idx] == nil {
+				customItemMap[idx] = map[int]*models.CustomItem{}
+			}
+			it := customItemMap[idx][itemIdx]
+			if it == nil {
+				it = &models.CustomItem{}
+				customItemMap[idx][itemIdx] = it
+			}
+			switch m[3] {
+			case "heading":
+				it.Heading = val
+			case "subtitle":
+				it.Subtitle = val
+			case "date":
+				it.Date = val
+			case "body":
+				it.Body = val
+			}
 		}
 	}
 
@@ -745,5 +1027,78 @@ func parseResumeFromForm(c *gin.Context) models.Resume {
 		}
 	}
 
+	if len(projMap) > 0 {
+		idxs := make([]int, 0, len(projMap))
+		for i := range projMap {
+			idxs = append(idxs, i)
+		}
+		sort.Ints(idxs)
+		for _, i := range idxs {
+			r.Projects = append(r.Projects, *projMap[i])
+		}
+	}
+
+	if len(certMap) > 0 {
+		idxs := make([]int, 0, len(certMap))
+		for i := range certMap {
+			idxs = append(idxs, i)
+		}
+		sort.Ints(idxs)
+		for _, i := range idxs {
+			r.Certifications = append(r.Certifications, *certMap[i])
+		}
+	}
+
+	if len(awardMap) > 0 {
+		idxs := make([]int, 0, len(awardMap))
+		for i := range awardMap {
+			idxs = append(idxs, i)
+		}
+		sort.Ints(idxs)
+		for _, i := range idxs {
+			r.Awards = append(r.Awards, *awardMap[i])
+		}
+	}
+
+	if len(skillMap) > 0 {
+		idxs := make([]int, 0, len(skillMap))
+		for i := range skillMap {
+			idxs = append(idxs, i)
+		}
+		sort.Ints(idxs)
+		for _, i := range idxs {
+			g := *skillMap[i]
+			itemIdxs := make([]int, 0, len(skillItemMap[i]))
+			for j := range skillItemMap[i] {
+				itemIdxs = append(itemIdxs, j)
+			}
+			sort.Ints(itemIdxs)
+			for _, j := range itemIdxs {
+				g.Items = append(g.Items, *skillItemMap[i][j])
+			}
+			r.Skills = append(r.Skills, g)
+		}
+	}
+
+	if len(customMap) > 0 {
+		idxs := make([]int, 0, len(customMap))
+		for i := range customMap {
+			idxs = append(idxs, i)
+		}
+		sort.Ints(idxs)
+		for _, i := range idxs {
+			cs := *customMap[i]
+			itemIdxs := make([]int, 0, len(customItemMap[i]))
+			for j := range customItemMap[i] {
+				itemIdxs = append(itemIdxs, j)
+			}
+			sort.Ints(itemIdxs)
+			for _, j := range itemIdxs {
+				cs.Items = append(cs.Items, *customItemMap[i][j])
+			}
+			r.Custom = append(r.Custom, cs)
+		}
+	}
+
 	return r
 }
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dongzhiwei-git/resume/config"
+	"github.com/dongzhiwei-git/resume/models"
+	"github.com/dongzhiwei-git/resume/validation"
+	"github.com/gin-gonic/gin"
+)
+
+func testRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.SetHTMLTemplate(template.Must(template.ParseGlob("../templates/*.html")))
+	r.POST("/preview", Preview)
+	r.POST("/api/preview_json", ApiPreviewJSON)
+	r.POST("/api/resumes", ApiCreateResume)
+	r.POST("/download/markdown", DownloadMarkdown)
+	return r
+}
+
+func post(r http.Handler, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// Previews render drafts without a name; saving and exporting require one.
+func TestValidationPaths(t *testing.T) {
+	r := testRouter(t)
+	draft := `{"summary":"写到一半","experience":[{"title":"工程师"}]}`
+	tests := []struct {
+		path, body string
+		want       int
+	}{
+		{"/api/preview_json", draft, http.StatusOK},
+		{"/api/preview_json", `{"email":"x@"}`, http.StatusUnprocessableEntity},
+		{"/api/resumes", draft, http.StatusUnprocessableEntity},
+		{"/download/markdown", draft, http.StatusUnprocessableEntity},
+		{"/download/markdown", `{"name":"张三"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := post(r, tt.path, tt.body)
+		if w.Code != tt.want {
+			t.Errorf("POST %s %s: got %d, want %d: %s", tt.path, tt.body, w.Code, tt.want, w.Body.String())
+		}
+	}
+	if w := post(r, "/api/resumes", draft); !strings.Contains(w.Body.String(), `"field":"name"`) {
+		t.Errorf("missing name error: %s", w.Body.String())
+	}
+}
+
+// The full-page preview is a form navigation: problems come back as a page,
+// not as JSON.
+func TestPreviewInvalidPage(t *testing.T) {
+	r := testRouter(t)
+	for _, tt := range []struct {
+		name string
+		want int
+		text string
+	}{
+		{"", http.StatusUnprocessableEntity, "<code>name</code>"},
+		{"张三", http.StatusOK, "张三"},
+	} {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		if err := mw.WriteField("name", tt.name); err != nil {
+			t.Fatal(err)
+		}
+		mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/preview", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") || !strings.Contains(w.Body.String(), tt.text) {
+			t.Errorf("name %q: %d %s, want %d with %q", tt.name, w.Code, w.Header().Get("Content-Type"), tt.want, tt.text)
+		}
+	}
+}
+
+func TestListConversations(t *testing.T) {
+	t.Setenv("AI_API_KEYS", "key-a,key-b")
+	enabled := config.AppConfig.EnableAIAssistant
+	config.AppConfig.EnableAIAssistant = true
+	t.Cleanup(func() { config.AppConfig.EnableAIAssistant = enabled })
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/ai/conversations", ApiCreateConversation)
+	r.GET("/api/ai/conversations", ApiListConversations)
+	do := func(method, path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	for i := 0; i < 3; i++ {
+		if w := do(http.MethodPost, "/api/ai/conversations", "key-a"); w.Code != http.StatusCreated {
+			t.Fatalf("create: %d %s", w.Code, w.Body.String())
+		}
+	}
+	do(http.MethodPost, "/api/ai/conversations", "key-b")
+
+	var page struct {
+		Conversations []struct{ ID string } `json:"conversations"`
+		Total         int                   `json:"total"`
+	}
+	w := do(http.MethodGet, "/api/ai/conversations?limit=2", "key-a")
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("list: %d %s", w.Code, w.Body.String())
+	}
+	if page.Total != 3 || len(page.Conversations) != 2 {
+		t.Errorf("key-a sees %d of %d", len(page.Conversations), page.Total)
+	}
+	for path, want := range map[string]int{
+		"/api/ai/conversations?limit=0":   http.StatusBadRequest,
+		"/api/ai/conversations?limit=101": http.StatusBadRequest,
+		"/api/ai/conversations?offset=-1": http.StatusBadRequest,
+		"/api/ai/conversations?offset=9":  http.StatusOK,
+	} {
+		if w := do(http.MethodGet, path, "key-a"); w.Code != want {
+			t.Errorf("GET %s: %d, want %d", path, w.Code, want)
+		}
+	}
+	if w := do(http.MethodGet, "/api/ai/conversations", "made-up"); w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown key: %d", w.Code)
+	}
+}
+
+// Without an API key, conversations belong to the cookie issued on create,
+// not to the client IP, and nobody else can read or delete them by ID.
+func TestConversationOwner(t *testing.T) {
+	t.Setenv("AI_API_KEYS", "key-a")
+	enabled := config.AppConfig.EnableAIAssistant
+	config.AppConfig.EnableAIAssistant = true
+	t.Cleanup(func() { config.AppConfig.EnableAIAssistant = enabled })
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/ai/conversations", ApiCreateConversation)
+	r.GET("/api/ai/conversations", ApiListConversations)
+	r.GET("/api/ai/conversations/:id", ApiGetConversation)
+	r.DELETE("/api/ai/conversations/:id", ApiDeleteConversation)
+	do := func(method, path string, set func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if set != nil {
+			set(req)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	create := func(set func(*http.Request)) (id string, cookie *http.Cookie) {
+		w := do(http.MethodPost, "/api/ai/conversations", set)
+		var conv struct{ ID string }
+		if err := json.Unmarshal(w.Body.Bytes(), &conv); err != nil || w.Code != http.StatusCreated {
+			t.Fatalf("create: %d %s", w.Code, w.Body.String())
+		}
+		for _, ck := range w.Result().Cookies() {
+			if ck.Name == ownerCookie {
+				cookie = ck
+			}
+		}
+		return conv.ID, cookie
+	}
+	withCookie := func(ck *http.Cookie) func(*http.Request) {
+		return func(req *http.Request) { req.AddCookie(ck) }
+	}
+	withKey := func(req *http.Request) { req.Header.Set("X-API-Key", "key-a") }
+
+	mine, ck := create(nil)
+	if ck == nil || !ck.HttpOnly {
+		t.Fatalf("owner cookie: %+v", ck)
+	}
+	if again, ck2 := create(withCookie(ck)); ck2 != nil || again == mine {
+		t.Errorf("second create reissued the cookie: %+v", ck2)
+	}
+	theirs, other := create(nil)
+	keyed, _ := create(withKey)
+
+	tests := []struct {
+		name   string
+		method string
+		id     string
+		set    func(*http.Request)
+		want   int
+	}{
+		{"owner reads", http.MethodGet, mine, withCookie(ck), http.StatusOK},
+		{"no cookie", http.MethodGet, mine, nil, http.StatusNotFound},
+		{"other cookie", http.MethodGet, mine, withCookie(other), http.StatusNotFound},
+		{"api key", http.MethodGet, mine, withKey, http.StatusNotFound},
+		{"cookie reads keyed", http.MethodGet, keyed, withCookie(ck), http.StatusNotFound},
+		{"key reads keyed", http.MethodGet, keyed, withKey, http.StatusOK},
+		{"other deletes", http.MethodDelete, mine, withCookie(other), http.StatusNotFound},
+		{"owner deletes", http.MethodDelete, theirs, withCookie(other), http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		if w := do(tt.method, "/api/ai/conversations/"+tt.id, tt.set); w.Code != tt.want {
+			t.Errorf("%s: %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+
+	var page struct{ Total int }
+	list := func(set func(*http.Request)) int {
+		w := do(http.MethodGet, "/api/ai/conversations", set)
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("list: %d %s", w.Code, w.Body.String())
+		}
+		return page.Total
+	}
+	if n := list(withCookie(ck)); n != 2 {
+		t.Errorf("owner lists %d, want 2", n)
+	}
+	if n := list(nil); n != 0 {
+		t.Errorf("caller without a cookie lists %d", n)
+	}
+	if n := list(withCookie(other)); n != 0 {
+		t.Errorf("other cookie lists %d after deleting its only conversation", n)
+	}
+}
+
+// A client cannot get a fresh quota by making up X-Forwarded-For; only the
+// address a trusted proxy appended counts.
+func TestQuotaIgnoresForgedForwardedFor(t *testing.T) {
+	t.Setenv("AI_API_KEYS", "")
+	t.Setenv("AI_DAILY_REQUEST_QUOTA", "1")
+	tests := []struct {
+		name, proxies, remote string
+		forwarded             []string
+	}{
+		{"direct", "", "192.0.2.10:1234", []string{"203.0.113.1", "203.0.113.2"}},
+		{"behind proxy", "192.0.2.1", "192.0.2.1:1234", []string{"203.0.113.1, 198.51.100.7", "203.0.113.2, 198.51.100.7"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", tt.proxies)
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			if err := r.SetTrustedProxies(TrustedProxies()); err != nil {
+				t.Fatal(err)
+			}
+			r.POST("/api/ai/ask", AIQuota, func(c *gin.Context) { c.Status(http.StatusOK) })
+			for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+				req := httptest.NewRequest(http.MethodPost, "/api/ai/ask", nil)
+				req.RemoteAddr = tt.remote
+				req.Header.Set("X-Forwarded-For", tt.forwarded[i])
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if w.Code != want {
+					t.Errorf("request %d with X-Forwarded-For %q: %d, want %d", i+1, tt.forwarded[i], w.Code, want)
+				}
+			}
+		})
+	}
+}
+
+// The repair loop asks the model to fix formats and dates, never to fill in
+// a company or school the user did not give.
+func TestCheckResume(t *testing.T) {
+	r := models.Resume{Experience: []models.Exp{{Title: "工程师", Date: "2020.06 - 至今"}}}
+	if err := checkResume(&r)(); err != nil {
+		t.Errorf("missing company: %v", err)
+	}
+	r.Email = "x@"
+	r.Experience[0].Date = "2022 - 2020"
+	err := checkResume(&r)()
+	var errs validation.Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Errorf("bad email and dates: %v", err)
+	}
+}
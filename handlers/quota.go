@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dongzhiwei-git/resume/ai"
+	"github.com/dongzhiwei-git/resume/metrics"
+	"github.com/dongzhiwei-git/resume/usage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const aiUsageKey = "ai_usage"
+
+// TrustedProxies lists the proxies whose X-Forwarded-For is believed when
+// working out the client IP, from the comma-separated TRUSTED_PROXIES (IPs
+// or CIDRs). It defaults to loopback only, so a client cannot pick its own
+// address, and with it a fresh AI quota, by sending X-Forwarded-For.
+func TrustedProxies() []string {
+	var out []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	if len(out) == 0 {
+		return []string{"127.0.0.1", "::1"}
+	}
+	return out
+}
+
+// aiClient identifies the caller for accounting: the API key from
+// X-API-Key when it is one of AI_API_KEYS (hashed, so keys never reach the
+// database), otherwise the client IP as resolved through TrustedProxies. ok is false for a key that is not
+// listed, so that made-up keys cannot be used to reset the quota.
+func aiClient(c *gin.Context) (client string, ok bool) {
+	key := strings.TrimSpace(c.GetHeader("X-API-Key"))
+	if key == "" || os.Getenv("AI_API_KEYS") == "" {
+		return "ip:" + c.ClientIP(), true
+	}
+	for _, k := range strings.Split(os.Getenv("AI_API_KEYS"), ",") {
+		if k = strings.TrimSpace(k); k != "" && subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			sum := sha256.Sum256([]byte(key))
+			return "key:" + hex.EncodeToString(sum[:8]), true
+		}
+	}
+	return "", false
+}
+
+// AIQuota guards /api/ai/*: it answers 429 once the client has used up its
+// daily quota, and otherwise counts the request against it before the
+// handler runs, then records the tokens the handler's provider calls
+// reported.
+func AIQuota(c *gin.Context) {
+	client, ok := aiClient(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid API key"})
+		return
+	}
+	quota := usage.QuotaFromEnv()
+	if requests, tokens, ok := usage.Reserve(client, quota); !ok {
+		now := time.Now()
+		midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+		c.Header("Retry-After", strconv.Itoa(int(midnight.Sub(now).Seconds())+1))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error": "AI quota exceeded",
+			"used":  gin.H{"requests": requests, "tokens": tokens},
+			"quota": quota,
+		})
+		return
+	}
+	u := &ai.Usage{}
+	c.Set(aiUsageKey, u)
+	c.Next()
+	usage.Add(client, strings.TrimPrefix(c.FullPath(), "/api/ai/"), *u)
+}
+
+// AICacheHeader sets X-AI-Cache to HIT when every model call of the
+// request was answered from the reply cache, MISS otherwise. Without a
+// cache the header is left out.
+func AICacheHeader(c *gin.Context) {
+	missed := false
+	ctx := ai.OnCacheLookup(c.Request.Context(), func(hit bool) {
+		if !hit {
+			missed = true
+		}
+		if missed {
+			c.Header("X-AI-Cache", "MISS")
+		} else {
+			c.Header("X-AI-Cache", "HIT")
+		}
+	})
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+// aiProvider returns the configured provider, with every call that reaches
+// the backend counted in the process metrics and metered into the request's
+// usage when it went through AIQuota. Reply cache hits are not counted.
+func aiProvider(c *gin.Context) ai.Provider {
+	var p ai.Provider
+	observers := []func(string, ai.Usage, error){func(call string, u ai.Usage, err error) {
+		metrics.ObserveAICall(p.Name(), call, u.PromptTokens, u.CompletionTokens, err)
+	}}
+	if v, ok := c.Get(aiUsageKey); ok {
+		total := v.(*ai.Usage)
+		observers = append(observers, func(_ string, u ai.Usage, _ error) { total.Add(u) })
+	}
+	p = ai.FromEnv(observers...)
+	return p
+}
+
+// ApiAdminAIUsage lists AI usage per client and endpoint for ?day=YYYY-MM-DD
+// (default today). It requires "Authorization: Bearer $ADMIN_TOKEN" and is
+// disabled while ADMIN_TOKEN is unset.
+func ApiAdminAIUsage(c *gin.Context) {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		c.String(http.StatusForbidden, "Disabled")
+		return
+	}
+	got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+		c.String(http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	day := c.DefaultQuery("day", usage.Today())
+	if _, err := time.Parse("2006-01-02", day); err != nil {
+		c.String(http.StatusBadRequest, "Invalid day")
+		return
+	}
+	rows, err := usage.Day(day)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Storage error")
+		return
+	}
+	var total ai.Usage
+	var requests int64
+	for _, r := range rows {
+		total.Add(r.Usage)
+		requests += r.Requests
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"day":   day,
+		"rows":  rows,
+		"total": gin.H{"requests": requests, "prompt_tokens": total.PromptTokens, "completion_tokens": total.CompletionTokens, "total_tokens": total.TotalTokens},
+		"quota": usage.QuotaFromEnv(),
+	})
+}
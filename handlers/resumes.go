@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/dongzhiwei-git/resume/config"
+	"github.com/dongzhiwei-git/resume/metrics"
+	"github.com/dongzhiwei-git/resume/models"
+	"github.com/dongzhiwei-git/resume/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func shareURL(c *gin.Context, slug string) string {
+	scheme := c.Request.Header.Get("X-Forwarded-Proto")
+	if scheme == "" {
+		scheme = "http"
+	}
+	return scheme + "://" + c.Request.Host + "/r/" + slug
+}
+
+func recordResponse(c *gin.Context, rec storage.Record) gin.H {
+	return gin.H{"id": rec.ID, "slug": rec.Slug, "url": shareURL(c, rec.Slug), "resume": rec.Resume}
+}
+
+func storageError(c *gin.Context, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		c.String(http.StatusNotFound, "Resume not found")
+		return
+	}
+	log.Printf("resume storage err: %v", err)
+	c.String(http.StatusInternalServerError, "Storage error")
+}
+
+func ApiCreateResume(c *gin.Context) {
+	resume, ok := bindResume(c)
+	if !ok || !validResume(c, resume) {
+		return
+	}
+	rec, err := storage.Create(resume)
+	if err != nil {
+		storageError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, recordResponse(c, rec))
+}
+
+func ApiGetResume(c *gin.Context) {
+	rec, err := storage.Get(c.Param("id"))
+	if err != nil {
+		storageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, recordResponse(c, rec))
+}
+
+func ApiUpdateResume(c *gin.Context) {
+	resume, ok := bindResume(c)
+	if !ok || !validResume(c, resume) {
+		return
+	}
+	rec, err := storage.Update(c.Param("id"), resume)
+	if err != nil {
+		storageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, recordResponse(c, rec))
+}
+
+func SharedResume(c *gin.Context) {
+	rec, err := storage.GetBySlug(c.Param("slug"))
+	if err != nil {
+		storageError(c, err)
+		return
+	}
+	resume := rec.Resume
+	if resume.Config.Color == "" {
+		resume.Config.Color = "#333333"
+	}
+	if resume.Config.Template == "" {
+		resume.Config.Template = "classic"
+	}
+
+	v, g := metrics.Snapshot()
+	c.HTML(http.StatusOK, "view.html", gin.H{
+		"title":  resume.Name + " - 简历",
+		"Resume": resume,
+		"ResumeJSON": func() string {
+			b, err := json.Marshal(resume)
+			if err != nil {
+				return "{}"
+			}
+			return string(b)
+		}(),
+		"Visits":       v,
+		"Generates":    g,
+		"Canonical":    shareURL(c, rec.Slug),
+		"ServerConfig": config.AppConfig,
+	})
+}
+
+func loadSavedResume(id string) (models.Resume, bool) {
+	if id == "" {
+		return models.Resume{}, false
+	}
+	rec, err := storage.Get(id)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("resume storage err: %v", err)
+		}
+		return models.Resume{}, false
+	}
+	return rec.Resume, true
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseHeartbeat is how often a comment line is sent while a stream is idle,
+// well within the 30s proxy_read_timeout of deploy/nginx.local.conf.
+const sseHeartbeat = 10 * time.Second
+
+// sseStream writes the assistant's server-sent events:
+//
+//	event: delta  data: {"content":"..."}   a piece of the reply
+//	event: usage  data: {"prompt_tokens":..,"completion_tokens":..,"total_tokens":..}
+//	event: error  data: {"error":"..."}     the reply did not complete
+//	event: done   data: {}                  the reply is complete
+//
+// The response is committed lazily, on the first event or heartbeat, so
+// that failures before then still get a plain HTTP error status. Writes
+// are serialised with the heartbeat goroutine.
+type sseStream struct {
+	c       *gin.Context
+	fl      http.Flusher
+	mu      sync.Mutex
+	started bool
+	err     error // first write error, usually a gone client
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+func newSSEStream(c *gin.Context) (*sseStream, bool) {
+	fl, ok := c.Writer.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	s := &sseStream{c: c, fl: fl, stop: make(chan struct{})}
+	s.stopped.Add(1)
+	go s.heartbeat()
+	return s, true
+}
+
+func (s *sseStream) heartbeat() {
+	defer s.stopped.Done()
+	t := time.NewTicker(sseHeartbeat)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			s.mu.Lock()
+			s.write(": ping\n\n")
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Close stops the heartbeat. It reports whether the response was committed;
+// if not, the caller still owns it.
+func (s *sseStream) Close() bool {
+	close(s.stop)
+	s.stopped.Wait()
+	return s.started
+}
+
+// write sends raw bytes, committing the response first. Callers hold mu.
+func (s *sseStream) write(raw string) error {
+	if s.err != nil {
+		return s.err
+	}
+	if !s.started {
+		h := s.c.Writer.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("Connection", "keep-alive")
+		// Tells nginx not to buffer the stream.
+		h.Set("X-Accel-Buffering", "no")
+		s.c.Writer.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+	if _, err := fmt.Fprint(s.c.Writer, raw); err != nil {
+		s.err = err
+		return err
+	}
+	s.fl.Flush()
+	return nil
+}
+
+// Event sends one event with data encoded as JSON.
+func (s *sseStream) Event(name string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(fmt.Sprintf("event: %s\ndata: %s\n\n", name, b))
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/dongzhiwei-git/resume/ai"
+	"github.com/dongzhiwei-git/resume/config"
+	"github.com/dongzhiwei-git/resume/keywords"
+	"github.com/dongzhiwei-git/resume/metrics"
+	"github.com/dongzhiwei-git/resume/models"
+	"github.com/dongzhiwei-git/resume/plaintext"
+
+	"github.com/gin-gonic/gin"
+)
+
+type tailorReq struct {
+	Resume         models.Resume `json:"resume"`
+	JobDescription string        `json:"job_description"`
+}
+
+// ApiAiTailor rewrites a resume for one job description through the revise
+// flow and reports which of the posting's keywords the result mentions.
+func ApiAiTailor(c *gin.Context) {
+	if !config.AppConfig.EnableAIAssistant {
+		c.String(http.StatusForbidden, "Disabled")
+		return
+	}
+	var reqBody tailorReq
+	if err := c.ShouldBindJSON(&reqBody); err != nil || strings.TrimSpace(reqBody.JobDescription) == "" {
+		c.String(http.StatusBadRequest, "Invalid input")
+		return
+	}
+	base := reqBody.Resume
+	instruction := "针对以下职位描述定制简历：调整措辞与条目顺序，突出与岗位相关的已有经历和技能；" +
+		"可以使用职位描述中的术语描述简历里已有的事实，但不得新增简历中没有的经历、技能、证书或数据。\n职位描述：\n" +
+		reqBody.JobDescription
+	r, outcome, err := reviseResume(c.Request.Context(), aiProvider(c), base, instruction)
+	if err != nil {
+		if errors.Is(err, ai.ErrNotConfigured) {
+			aiError(c, err)
+			return
+		}
+		log.Printf("ai tailor: %v", err)
+	}
+	// Tailoring changes content only; keep the user's layout settings.
+	r.Config = base.Config
+	r = withThemeDefaults(r)
+	setAIOutcome(c, outcome)
+	countAIResume(metrics.EventAIRevise, outcome)
+	c.JSON(http.StatusOK, gin.H{
+		"resume":  r,
+		"outcome": outcome,
+		"report":  keywords.Compare(reqBody.JobDescription, plaintext.String(base), plaintext.String(r)),
+	})
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/dongzhiwei-git/resume/ai"
+	"github.com/dongzhiwei-git/resume/config"
+	"github.com/dongzhiwei-git/resume/models"
+	"github.com/dongzhiwei-git/resume/translate"
+
+	"github.com/gin-gonic/gin"
+)
+
+type translateReq struct {
+	Resume models.Resume `json:"resume"`
+	Locale string        `json:"locale"`
+}
+
+// ApiAiTranslate translates the content of a resume into another UI locale.
+// Whatever must stay identical (contact details, dates, links, layout) is
+// checked after the model answers and put back if it changed; the response
+// lists those fields under "restored".
+func ApiAiTranslate(c *gin.Context) {
+	if !config.AppConfig.EnableAIAssistant {
+		c.String(http.StatusForbidden, "Disabled")
+		return
+	}
+	var reqBody translateReq
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		c.String(http.StatusBadRequest, "Invalid input")
+		return
+	}
+	lang, ok := translate.Locales[reqBody.Locale]
+	if !ok {
+		c.String(http.StatusBadRequest, "Unsupported locale")
+		return
+	}
+	orig := reqBody.Resume
+	// Layout and avatar are not text; leave them out of the prompt.
+	content := orig
+	content.Avatar = ""
+	content.Config = models.ThemeConfig{}
+	contentJSON, _ := json.Marshal(content)
+
+	sys := ai.Message{Role: "system", Content: "你是简历翻译助手。把 JSON 简历中的文字内容翻译成" + lang + "，保持 JSON 结构、键名和数组条目数量不变。" +
+		"以下内容必须原样保留，不得翻译或改写：邮箱、电话、链接、所有 date 字段、人名、公司名、学校名、项目名称、证书与奖项的颁发机构（issuer）、产品名与技术名词（如 Go、MySQL、Kubernetes）。只返回 JSON。"}
+	user := ai.Message{Role: "user", Content: "目标语言：" + lang + "\n简历：" + string(contentJSON) + "\n只返回 JSON，不要解释。"}
+
+	var tr models.Resume
+	if _, err := aiProvider(c).JSON(c.Request.Context(), []ai.Message{sys, user}, &tr); err != nil {
+		aiError(c, err)
+		return
+	}
+	tr.Avatar = orig.Avatar
+	tr.Config = orig.Config
+	restored, err := translate.Enforce(orig, &tr)
+	if err != nil {
+		aiError(c, fmt.Errorf("%w: %v", ai.ErrBadResponse, err))
+		return
+	}
+	if restored == nil {
+		restored = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{"resume": tr, "restored": restored})
+}
@@ -0,0 +1,339 @@
+// Package jsonresume converts between models.Resume and the open JSON Resume
+// schema (https://jsonresume.org/schema).
+//
+// The two models differ, so the conversion loses some fields. Import drops
+// basics.url, basics.location and basics.profiles, the url of work,
+// education and certificates, education score and courses, and project
+// keywords, entity and type; basics.label is used only as a missing
+// summary, highlights are appended to the description and an education's
+// area is joined to its degree. Export drops custom sections and the theme
+// config, and keeps a skill group's levels only when all its items share
+// one, since a JSON Resume skill has a single level.
+package jsonresume
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/dongzhiwei-git/resume/models"
+)
+
+type Resume struct {
+	Basics       Basics        `json:"basics"`
+	Work         []Work        `json:"work,omitempty"`
+	Education    []Education   `json:"education,omitempty"`
+	Skills       []Skill       `json:"skills,omitempty"`
+	Projects     []Project     `json:"projects,omitempty"`
+	Awards       []Award       `json:"awards,omitempty"`
+	Certificates []Certificate `json:"certificates,omitempty"`
+	Languages    []Language    `json:"languages,omitempty"`
+}
+
+type Basics struct {
+	Name     string    `json:"name"`
+	Label    string    `json:"label,omitempty"`
+	Image    string    `json:"image,omitempty"`
+	Email    string    `json:"email,omitempty"`
+	Phone    string    `json:"phone,omitempty"`
+	URL      string    `json:"url,omitempty"`
+	Summary  string    `json:"summary,omitempty"`
+	Location *Location `json:"location,omitempty"`
+	Profiles []Profile `json:"profiles,omitempty"`
+}
+
+type Location struct {
+	Address     string `json:"address,omitempty"`
+	PostalCode  string `json:"postalCode,omitempty"`
+	City        string `json:"city,omitempty"`
+	CountryCode string `json:"countryCode,omitempty"`
+	Region      string `json:"region,omitempty"`
+}
+
+type Profile struct {
+	Network  string `json:"network,omitempty"`
+	Username string `json:"username,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+type Work struct {
+	Name       string   `json:"name"`
+	Position   string   `json:"position,omitempty"`
+	URL        string   `json:"url,omitempty"`
+	StartDate  string   `json:"startDate,omitempty"`
+	EndDate    string   `json:"endDate,omitempty"`
+	Summary    string   `json:"summary,omitempty"`
+	Highlights []string `json:"highlights,omitempty"`
+}
+
+type Education struct {
+	Institution string   `json:"institution"`
+	URL         string   `json:"url,omitempty"`
+	Area        string   `json:"area,omitempty"`
+	StudyType   string   `json:"studyType,omitempty"`
+	StartDate   string   `json:"startDate,omitempty"`
+	EndDate     string   `json:"endDate,omitempty"`
+	Score       string   `json:"score,omitempty"`
+	Courses     []string `json:"courses,omitempty"`
+}
+
+type Skill struct {
+	Name     string   `json:"name"`
+	Level    string   `json:"level,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+type Project struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Highlights  []string `json:"highlights,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	StartDate   string   `json:"startDate,omitempty"`
+	EndDate     string   `json:"endDate,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Entity      string   `json:"entity,omitempty"`
+	Type        string   `json:"type,omitempty"`
+}
+
+type Award struct {
+	Title   string `json:"title"`
+	Date    string `json:"date,omitempty"`
+	Awarder string `json:"awarder,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+type Certificate struct {
+	Name   string `json:"name"`
+	Date   string `json:"date,omitempty"`
+	Issuer string `json:"issuer,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+type Language struct {
+	Language string `json:"language"`
+	Fluency  string `json:"fluency,omitempty"`
+}
+
+// Detect reports whether data looks like a JSON Resume document rather than
+// this project's own resume JSON.
+func Detect(data []byte) bool {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return false
+	}
+	_, basics := probe["basics"]
+	_, work := probe["work"]
+	return basics || work
+}
+
+func FromResume(r models.Resume) Resume {
+	out := Resume{Basics: Basics{
+		Name:    r.Name,
+		Image:   r.Avatar,
+		Email:   r.Email,
+		Phone:   r.Phone,
+		Summary: r.Summary,
+	}}
+	for _, e := range r.Experience {
+		start, end := SplitDate(e.Date)
+		out.Work = append(out.Work, Work{
+			Name:      e.Company,
+			Position:  e.Title,
+			StartDate: start,
+			EndDate:   end,
+			Summary:   e.Description,
+		})
+	}
+	for _, e := range r.Education {
+		start, end := SplitDate(e.Date)
+		out.Education = append(out.Education, Education{
+			Institution: e.School,
+			StudyType:   e.Degree,
+			StartDate:   start,
+			EndDate:     end,
+		})
+	}
+	for _, p := range r.Projects {
+		start, end := SplitDate(p.Date)
+		pr := Project{
+			Name:        p.Name,
+			Description: p.Description,
+			StartDate:   start,
+			EndDate:     end,
+			URL:         p.URL,
+		}
+		if p.Role != "" {
+			pr.Roles = []string{p.Role}
+		}
+		out.Projects = append(out.Projects, pr)
+	}
+	for _, c := range r.Certifications {
+		out.Certificates = append(out.Certificates, Certificate{Name: c.Name, Issuer: c.Issuer, Date: isoDate(c.Date)})
+	}
+	for _, a := range r.Awards {
+		out.Awards = append(out.Awards, Award{Title: a.Title, Awarder: a.Issuer, Date: isoDate(a.Date), Summary: a.Description})
+	}
+	for _, g := range r.Skills {
+		if g.Category == languagesCategory {
+			for _, it := range g.Items {
+				out.Languages = append(out.Languages, Language{Language: it.Name, Fluency: it.Level})
+			}
+			continue
+		}
+		sk := Skill{Name: g.Category}
+		for _, it := range g.Items {
+			sk.Keywords = append(sk.Keywords, it.Name)
+		}
+		// JSON Resume has one level per skill; keep it when the group agrees.
+		sk.Level = commonLevel(g.Items)
+		out.Skills = append(out.Skills, sk)
+	}
+	return out
+}
+
+// languagesCategory is the skill group that maps to JSON Resume languages.
+const languagesCategory = "语言"
+
+func commonLevel(items []models.SkillItem) string {
+	if len(items) == 0 {
+		return ""
+	}
+	for _, it := range items[1:] {
+		if it.Level != items[0].Level {
+			return ""
+		}
+	}
+	return items[0].Level
+}
+
+func ToResume(j Resume) models.Resume {
+	r := models.Resume{
+		Name:    j.Basics.Name,
+		Email:   j.Basics.Email,
+		Phone:   j.Basics.Phone,
+		Avatar:  j.Basics.Image,
+		Summary: j.Basics.Summary,
+	}
+	if r.Summary == "" {
+		r.Summary = j.Basics.Label
+	}
+	for _, w := range j.Work {
+		desc := w.Summary
+		if len(w.Highlights) > 0 {
+			if desc != "" {
+				desc += "\n"
+			}
+			desc += strings.Join(w.Highlights, "\n")
+		}
+		r.Experience = append(r.Experience, models.Exp{
+			Title:       w.Position,
+			Company:     w.Name,
+			Date:        JoinDate(w.StartDate, w.EndDate),
+			Description: desc,
+		})
+	}
+	for _, e := range j.Education {
+		degree := strings.TrimSpace(strings.Join([]string{e.Area, e.StudyType}, " "))
+		r.Education = append(r.Education, models.Edu{
+			Degree: degree,
+			School: e.Institution,
+			Date:   JoinDate(e.StartDate, e.EndDate),
+		})
+	}
+	for _, p := range j.Projects {
+		desc := p.Description
+		if len(p.Highlights) > 0 {
+			if desc != "" {
+				desc += "\n"
+			}
+			desc += strings.Join(p.Highlights, "\n")
+		}
+		r.Projects = append(r.Projects, models.Project{
+			Name:        p.Name,
+			Role:        strings.Join(p.Roles, "、"),
+			Date:        JoinDate(p.StartDate, p.EndDate),
+			URL:         p.URL,
+			Description: desc,
+		})
+	}
+	for _, c := range j.Certificates {
+		r.Certifications = append(r.Certifications, models.Certification{Name: c.Name, Issuer: c.Issuer, Date: c.Date})
+	}
+	for _, a := range j.Awards {
+		r.Awards = append(r.Awards, models.Award{Title: a.Title, Issuer: a.Awarder, Date: a.Date, Description: a.Summary})
+	}
+	for _, sk := range j.Skills {
+		g := models.SkillGroup{Category: sk.Name}
+		for _, kw := range sk.Keywords {
+			g.Items = append(g.Items, models.SkillItem{Name: kw, Level: sk.Level})
+		}
+		if len(g.Items) == 0 && sk.Name != "" {
+			// A bare skill without keywords is itself the item.
+			g = models.SkillGroup{Items: []models.SkillItem{{Name: sk.Name, Level: sk.Level}}}
+		}
+		r.Skills = append(r.Skills, g)
+	}
+	if len(j.Languages) > 0 {
+		g := models.SkillGroup{Category: languagesCategory}
+		for _, l := range j.Languages {
+			g.Items = append(g.Items, models.SkillItem{Name: l.Language, Level: l.Fluency})
+		}
+		r.Skills = append(r.Skills, g)
+	}
+	return r
+}
+
+var dateRe = regexp.MustCompile(`(\d{4})(?:\s*[-./年]\s*(0?[1-9]|1[0-2])\b)?`)
+var ongoingRe = regexp.MustCompile(`(?i)至今|现在|目前|present|now|current`)
+
+// SplitDate extracts ISO-8601 start and end dates (YYYY or YYYY-MM) from a
+// free-form range such as "2021.03 - 至今" or "2014 - 2018". An ongoing range
+// yields an empty end date, matching the JSON Resume convention.
+func SplitDate(s string) (string, string) {
+	var dates []string
+	for _, m := range dateRe.FindAllStringSubmatch(s, 2) {
+		d := m[1]
+		if m[2] != "" {
+			if len(m[2]) == 1 {
+				m[2] = "0" + m[2]
+			}
+			d += "-" + m[2]
+		}
+		dates = append(dates, d)
+	}
+	switch len(dates) {
+	case 0:
+		return "", ""
+	case 1:
+		if ongoingRe.MatchString(s) || strings.HasSuffix(strings.TrimSpace(s), "-") {
+			return dates[0], ""
+		}
+		// A lone date such as a graduation year is treated as the end.
+		return "", dates[0]
+	}
+	return dates[0], dates[1]
+}
+
+// isoDate normalises a single free-form date such as "2021.06" to YYYY-MM.
+func isoDate(s string) string {
+	if start, end := SplitDate(s); end != "" {
+		return end
+	} else if start != "" {
+		return start
+	}
+	return s
+}
+
+// JoinDate is the inverse of SplitDate.
+func JoinDate(start, end string) string {
+	switch {
+	case start == "" && end == "":
+		return ""
+	case start == "":
+		return end
+	case end == "":
+		return start + " - 至今"
+	}
+	return start + " - " + end
+}
@@ -0,0 +1,135 @@
+package jsonresume
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/dongzhiwei-git/resume/models"
+)
+
+const full = `{
+  "basics": {
+    "name": "Jane Doe", "label": "Engineer", "image": "/static/uploads/jane.png",
+    "email": "jane@example.com", "phone": "+1 555 0100", "url": "https://jane.dev",
+    "summary": "Builds things.",
+    "location": {"city": "Shanghai", "countryCode": "CN"},
+    "profiles": [{"network": "GitHub", "username": "jane", "url": "https://github.com/jane"}]
+  },
+  "work": [
+    {"name": "Acme", "position": "Backend Engineer", "url": "https://acme.example", "startDate": "2020-06",
+     "summary": "Payments.", "highlights": ["Cut latency by 40%"]},
+    {"name": "Initech", "position": "Intern", "startDate": "2019-01", "endDate": "2019-06"}
+  ],
+  "education": [
+    {"institution": "MIT", "url": "https://mit.edu", "area": "CS", "studyType": "BS",
+     "startDate": "2014", "endDate": "2018", "score": "3.9", "courses": ["Algorithms"]}
+  ],
+  "skills": [
+    {"name": "Backend", "level": "Advanced", "keywords": ["Go", "MySQL"]},
+    {"name": "Docker", "level": "Intermediate"}
+  ],
+  "projects": [
+    {"name": "resume", "description": "Resume builder.", "highlights": ["Open source"], "keywords": ["Go"],
+     "startDate": "2023-01", "url": "https://github.com/jane/resume", "roles": ["Author"],
+     "entity": "Personal", "type": "application"}
+  ],
+  "awards": [{"title": "Hackathon winner", "date": "2021-05", "awarder": "ACME", "summary": "First place."}],
+  "certificates": [{"name": "CKA", "date": "2022-03", "issuer": "CNCF", "url": "https://cncf.io/cka"}],
+  "languages": [{"language": "English", "fluency": "Fluent"}]
+}`
+
+// A JSON Resume document survives import and export except for the fields
+// the package documentation lists as dropped.
+func TestRoundTrip(t *testing.T) {
+	var in Resume
+	if err := json.Unmarshal([]byte(full), &in); err != nil {
+		t.Fatal(err)
+	}
+	got := FromResume(ToResume(in))
+	want := Resume{
+		// label, url, location and profiles are dropped.
+		Basics: Basics{
+			Name: "Jane Doe", Image: "/static/uploads/jane.png",
+			Email: "jane@example.com", Phone: "+1 555 0100", Summary: "Builds things.",
+		},
+		// url is dropped; highlights are folded into the summary.
+		Work: []Work{
+			{Name: "Acme", Position: "Backend Engineer", StartDate: "2020-06", Summary: "Payments.\nCut latency by 40%"},
+			{Name: "Initech", Position: "Intern", StartDate: "2019-01", EndDate: "2019-06"},
+		},
+		// url, score and courses are dropped; area joins the degree.
+		Education: []Education{
+			{Institution: "MIT", StudyType: "CS BS", StartDate: "2014", EndDate: "2018"},
+		},
+		// A skill without keywords comes back as a nameless group holding it.
+		Skills: []Skill{
+			{Name: "Backend", Level: "Advanced", Keywords: []string{"Go", "MySQL"}},
+			{Level: "Intermediate", Keywords: []string{"Docker"}},
+		},
+		// keywords, entity and type are dropped; highlights are folded into
+		// the description.
+		Projects: []Project{
+			{Name: "resume", Description: "Resume builder.\nOpen source", StartDate: "2023-01",
+				URL: "https://github.com/jane/resume", Roles: []string{"Author"}},
+		},
+		Awards: []Award{{Title: "Hackathon winner", Date: "2021-05", Awarder: "ACME", Summary: "First place."}},
+		// url is dropped.
+		Certificates: []Certificate{{Name: "CKA", Date: "2022-03", Issuer: "CNCF"}},
+		Languages:    []Language{{Language: "English", Fluency: "Fluent"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		g, _ := json.MarshalIndent(got, "", "  ")
+		w, _ := json.MarshalIndent(want, "", "  ")
+		t.Errorf("round trip:\n got %s\nwant %s", g, w)
+	}
+}
+
+// Exporting keeps a skill group's level only when every item has it, and
+// drops custom sections and the theme config.
+func TestFromResumeDrops(t *testing.T) {
+	r := models.Resume{
+		Name: "张三",
+		Skills: []models.SkillGroup{
+			{Category: "后端", Items: []models.SkillItem{{Name: "Go", Level: "精通"}, {Name: "MySQL", Level: "熟练"}}},
+			{Category: "前端", Items: []models.SkillItem{{Name: "Vue", Level: "熟练"}, {Name: "CSS", Level: "熟练"}}},
+		},
+		Custom: []models.CustomSection{{Title: "论文", Items: []models.CustomItem{{Heading: "A paper"}}}},
+		Config: models.ThemeConfig{Template: "modern", Color: "#123456"},
+	}
+	back := ToResume(FromResume(r))
+	want := []models.SkillGroup{
+		{Category: "后端", Items: []models.SkillItem{{Name: "Go"}, {Name: "MySQL"}}},
+		{Category: "前端", Items: []models.SkillItem{{Name: "Vue", Level: "熟练"}, {Name: "CSS", Level: "熟练"}}},
+	}
+	if !reflect.DeepEqual(back.Skills, want) {
+		t.Errorf("skills: %+v", back.Skills)
+	}
+	if back.Custom != nil || !reflect.DeepEqual(back.Config, models.ThemeConfig{}) {
+		t.Errorf("custom %+v, config %+v", back.Custom, back.Config)
+	}
+}
+
+func TestSplitDate(t *testing.T) {
+	tests := []struct {
+		in, start, end string
+	}{
+		{"2021.03 - 至今", "2021-03", ""},
+		{"2014 - 2018", "2014", "2018"},
+		{"2019年9月 - 2020年6月", "2019-09", "2020-06"},
+		{"2020-06", "", "2020-06"},
+		{"2020-06 -", "2020-06", ""},
+		{"Jan 2020 - present", "2020", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if start, end := SplitDate(tt.in); start != tt.start || end != tt.end {
+			t.Errorf("SplitDate(%q) = %q, %q; want %q, %q", tt.in, start, end, tt.start, tt.end)
+		}
+		if tt.start != "" || tt.end != "" {
+			if start, end := SplitDate(JoinDate(tt.start, tt.end)); start != tt.start || end != tt.end {
+				t.Errorf("JoinDate(%q, %q) does not split back: %q, %q", tt.start, tt.end, start, end)
+			}
+		}
+	}
+}
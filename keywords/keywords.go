@@ -0,0 +1,225 @@
+// Package keywords pulls skill and requirement keywords out of a job
+// description and checks which of them a resume mentions. It is purely
+// lexical: no AI call and no dictionary download, so results are stable.
+package keywords
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Max is the most keywords Extract returns.
+const Max = 60
+
+// Latin terms: Go, C++, C#, Node.js, CI/CD, K8s, gRPC ...
+var latinRe = regexp.MustCompile(`[A-Za-z][A-Za-z0-9]*(?:[+#]+|(?:[./-][A-Za-z0-9]+)+)?`)
+
+var latinStop = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`a an and or the of to in on for with by at as is are be
+		we you our your will can able etc e.g i.e plus years year experience experienced
+		work working team skills skill strong good knowledge understanding familiar
+		including include such other related least preferred required requirements
+		responsibilities job role position candidate ability must nice have has
+		using use build building develop developing design designing`) {
+		latinStop[w] = true
+	}
+}
+
+// CJK runs are cut at these function words and generic JD phrases so that
+// "熟悉分布式系统设计，有高并发经验者优先" yields 分布式系统设计 and 高并发.
+var cjkStop = []string{
+	"熟练掌握", "熟练使用", "熟悉", "掌握", "了解", "精通", "熟练", "具备", "具有", "拥有",
+	"负责", "参与", "能够", "良好的", "良好", "优秀的", "优秀", "较强的", "较强",
+	"相关", "以上", "优先", "经验", "能力", "工作", "岗位", "职责", "要求", "任职",
+	"以及", "包括", "一定", "至少", "本科", "学历", "专业",
+	// Single characters only where they rarely occur inside a term
+	// (not 并, 能 or 在: 高并发, 性能, 在线).
+	"者", "及", "与", "和", "或", "等", "的", "有", "年",
+}
+
+// Extract returns the distinct keywords of text in order of first appearance,
+// at most Max of them.
+func Extract(text string) []string {
+	var out []string
+	seen := map[string]bool{}
+	add := func(k string) {
+		key := strings.ToLower(k)
+		if seen[key] || len(out) >= Max {
+			return
+		}
+		seen[key] = true
+		out = append(out, k)
+	}
+	// Walk the text once so Latin and CJK keywords keep their relative order.
+	runs := splitRuns(text)
+	for _, run := range runs {
+		if run.cjk {
+			for _, seg := range cutCJK(run.text) {
+				add(seg)
+			}
+			continue
+		}
+		for _, w := range latinRe.FindAllString(run.text, -1) {
+			if len(w) < 2 && w != "C" && w != "R" {
+				continue
+			}
+			if latinStop[strings.ToLower(w)] {
+				continue
+			}
+			add(w)
+		}
+	}
+	return out
+}
+
+type run struct {
+	text string
+	cjk  bool
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
+
+func splitRuns(s string) []run {
+	var out []run
+	var b strings.Builder
+	cur := false
+	flush := func() {
+		if b.Len() > 0 {
+			out = append(out, run{b.String(), cur})
+			b.Reset()
+		}
+	}
+	for _, r := range s {
+		c := isCJK(r)
+		if c != cur {
+			flush()
+			cur = c
+		}
+		b.WriteRune(r)
+	}
+	flush()
+	return out
+}
+
+func cutCJK(s string) []string {
+	parts := []string{s}
+	for _, stop := range cjkStop {
+		var next []string
+		for _, p := range parts {
+			next = append(next, strings.Split(p, stop)...)
+		}
+		parts = next
+	}
+	var out []string
+	for _, p := range parts {
+		if n := utf8.RuneCountInString(p); n >= 2 && n <= 8 {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Contains reports whether text mentions keyword, ignoring case. Latin
+// keywords must match a whole word, so "Go" is not found in "Google". A
+// keyword joined with slashes, such as "CI/CD" or "TCP/IP", is also found
+// when every part is mentioned separately ("CI and CD"), but never through
+// one part alone.
+func Contains(text, keyword string) bool {
+	t, k := strings.ToLower(text), strings.ToLower(keyword)
+	if contains(t, k) {
+		return true
+	}
+	parts := strings.Split(k, "/")
+	if len(parts) < 2 {
+		return false
+	}
+	for _, p := range parts {
+		if !contains(t, p) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(t, k string) bool {
+	if k == "" {
+		return false
+	}
+	if !isLatinWord(k) {
+		return strings.Contains(t, k)
+	}
+	for i := 0; ; {
+		j := strings.Index(t[i:], k)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(k)
+		if !wordChar(lastRune(t[:start])) && !wordChar(firstRune(t[end:])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isLatinWord(k string) bool {
+	r, _ := utf8.DecodeRuneInString(k)
+	return r < utf8.RuneSelf
+}
+
+func wordChar(r rune) bool {
+	return r < utf8.RuneSelf && (r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+// Report compares the keywords of a job description with a resume before
+// and after rewriting.
+type Report struct {
+	Keywords []string `json:"keywords"`
+	// Found are the keywords the rewritten resume mentions.
+	Found []string `json:"found"`
+	// Missing are the keywords it still does not mention.
+	Missing []string `json:"missing"`
+	// Introduced are found keywords the original resume did not mention.
+	// They deserve a second look: the rewrite may have overstated something.
+	Introduced []string `json:"introduced"`
+	// Coverage is len(Found)/len(Keywords), or 0 without keywords.
+	Coverage float64 `json:"coverage"`
+}
+
+// Compare checks the keywords of jd against a resume's text before and after
+// rewriting.
+func Compare(jd, before, after string) Report {
+	rep := Report{Keywords: Extract(jd), Found: []string{}, Missing: []string{}, Introduced: []string{}}
+	for _, k := range rep.Keywords {
+		if !Contains(after, k) {
+			rep.Missing = append(rep.Missing, k)
+			continue
+		}
+		rep.Found = append(rep.Found, k)
+		if !Contains(before, k) {
+			rep.Introduced = append(rep.Introduced, k)
+		}
+	}
+	if rep.Keywords == nil {
+		rep.Keywords = []string{}
+	}
+	if n := len(rep.Keywords); n > 0 {
+		rep.Coverage = float64(len(rep.Found)) / float64(n)
+	}
+	return rep
+}
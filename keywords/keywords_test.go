@@ -0,0 +1,64 @@
+package keywords
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		text, keyword string
+		want          bool
+	}{
+		{"搭建 CI/CD 流水线", "CI/CD", true},
+		{"搭建 ci/cd 流水线", "CI/CD", true},
+		{"负责 CI 与 CD 流程", "CI/CD", true},
+		{"使用 CD 光盘", "CI/CD", false},
+		{"CI 覆盖率 90%", "CI/CD", false},
+		{"熟悉 TCP/IP 协议", "TCP/IP", true},
+		{"配置 IP 白名单", "TCP/IP", false},
+		{"TCP 长连接与 IP 路由", "TCP/IP", true},
+		{"Go, Python", "Go", true},
+		{"在 Google 实习", "Go", false},
+		{"精通 golang", "Go", false},
+		{"Node.js 服务端", "node.js", true},
+		{"熟悉c++开发", "C++", true},
+		{"有高并发系统经验", "高并发", true},
+		{"并发编程", "高并发", false},
+		{"", "Go", false},
+		{"Go", "", false},
+	}
+	for _, tt := range tests {
+		if got := Contains(tt.text, tt.keyword); got != tt.want {
+			t.Errorf("Contains(%q, %q) = %v, want %v", tt.text, tt.keyword, got, tt.want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		jd   string
+		want []string
+	}{
+		{"熟悉分布式系统设计，有高并发经验者优先", []string{"分布式系统设计", "高并发"}},
+		{"Experience with Go and Kubernetes; CI/CD, gRPC, C++ and C#", []string{"Go", "Kubernetes", "CI/CD", "gRPC", "C++", "C#"}},
+		{"Go 与 go 重复，Redis 或 redis", []string{"Go", "重复", "Redis"}},
+	}
+	for _, tt := range tests {
+		if got := Extract(tt.jd); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Extract(%q) = %q, want %q", tt.jd, got, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	rep := Compare("要求 Go、CI/CD、TCP/IP", "用 Go 写过服务，配置过 IP", "用 Go 写过服务，搭建 CI/CD，配置过 IP")
+	if !reflect.DeepEqual(rep.Found, []string{"Go", "CI/CD"}) ||
+		!reflect.DeepEqual(rep.Missing, []string{"TCP/IP"}) ||
+		!reflect.DeepEqual(rep.Introduced, []string{"CI/CD"}) {
+		t.Errorf("Compare: %+v", rep)
+	}
+	if rep.Coverage != 2.0/3 {
+		t.Errorf("coverage %v", rep.Coverage)
+	}
+}
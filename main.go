@@ -12,9 +12,13 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/dongzhiwei-git/resume/ai"
 	"github.com/dongzhiwei-git/resume/config"
+	"github.com/dongzhiwei-git/resume/conversations"
 	"github.com/dongzhiwei-git/resume/handlers"
 	"github.com/dongzhiwei-git/resume/metrics"
+	"github.com/dongzhiwei-git/resume/storage"
+	"github.com/dongzhiwei-git/resume/usage"
 	"github.com/gin-gonic/gin"
 )
 
@@ -36,14 +40,12 @@ func main() {
 			}
 			log.Printf("AI assistant enabled: %v", config.AppConfig.EnableAIAssistant)
 			router := gin.Default()
-			router.Use(func(c *gin.Context) {
-				if c.Request.Method == "GET" {
-					p := c.Request.URL.Path
-					if !strings.HasPrefix(p, "/static") && !strings.HasPrefix(p, "/.well-known") && p != "/robots.txt" && p != "/sitemap.xml" && p != "/favicon.ico" && p != "/metrics/snapshot" {
-						metrics.IncVisit()
-					}
-				}
-			})
+			if err := router.SetTrustedProxies(handlers.TrustedProxies()); err != nil {
+				log.Printf("TRUSTED_PROXIES: %v; trusting no proxy", err)
+				_ = router.SetTrustedProxies(nil)
+			}
+			router.Use(metrics.Instrument)
+			router.Use(metrics.CountVisit)
 			router.Static("/static", "./static")
 			tmpl := template.Must(template.ParseFS(templatesFS, "templates/*.html"))
 			router.SetHTMLTemplate(tmpl)
@@ -53,30 +55,79 @@ func main() {
 			router.POST("/preview", handlers.Preview)
 			router.POST("/api/preview", handlers.ApiPreview)
 			router.GET("/ai", handlers.AiPage)
-			router.POST("/api/ai/ask", handlers.ApiAiAsk)
-			router.POST("/api/ai/stream", handlers.ApiAiStream)
-			router.POST("/api/ai/generate_simple", handlers.ApiAiGenerateSimple)
-			router.POST("/api/ai/revise", handlers.ApiAiRevise)
+			aiAPI := router.Group("/api/ai", handlers.AIQuota, handlers.AICacheHeader)
+			aiAPI.POST("/ask", handlers.ApiAiAsk)
+			aiAPI.POST("/stream", handlers.ApiAiStream)
+			aiAPI.POST("/generate_simple", handlers.ApiAiGenerateSimple)
+			aiAPI.POST("/generate_stream", handlers.ApiAiGenerateStream)
+			aiAPI.POST("/revise", handlers.ApiAiRevise)
+			aiAPI.POST("/tailor", handlers.ApiAiTailor)
+			aiAPI.POST("/cover_letter", handlers.ApiAiCoverLetter)
+			aiAPI.POST("/translate", handlers.ApiAiTranslate)
+			aiAPI.POST("/conversations", handlers.ApiCreateConversation)
+			aiAPI.POST("/conversations/:id/messages", handlers.ApiAppendConversationMessage)
+			router.GET("/api/ai/conversations", handlers.ApiListConversations)
+			router.GET("/api/ai/conversations/:id", handlers.ApiGetConversation)
+			router.DELETE("/api/ai/conversations/:id", handlers.ApiDeleteConversation)
+			router.POST("/api/cover_letter/preview", handlers.ApiPreviewCoverLetter)
 			router.POST("/api/preview_json", handlers.ApiPreviewJSON)
+			router.POST("/api/validate", handlers.ApiValidate)
+			router.POST("/api/ats/score", handlers.ApiAtsScore)
 			router.POST("/download/pdf", handlers.DownloadPDF)
+			router.POST("/download/cover_letter/pdf", handlers.DownloadCoverLetterPDF)
+			router.POST("/download/docx", handlers.DownloadDOCX)
+			router.POST("/download/markdown", handlers.DownloadMarkdown)
+			router.POST("/download/txt", handlers.DownloadTXT)
 			router.POST("/import", handlers.Import)
+			router.POST("/api/convert/jsonresume", handlers.ApiConvertJSONResume)
+			router.POST("/api/resumes", handlers.ApiCreateResume)
+			router.GET("/api/resumes/:id", handlers.ApiGetResume)
+			router.PUT("/api/resumes/:id", handlers.ApiUpdateResume)
+			router.GET("/r/:slug", handlers.SharedResume)
 			router.GET("/robots.txt", handlers.Robots)
 			router.GET("/sitemap.xml", handlers.Sitemap)
 			router.POST("/metrics/generate", handlers.GenerateEvent)
 			router.GET("/metrics/snapshot", handlers.SnapshotAPI)
+			router.GET("/metrics", handlers.PrometheusMetrics)
+			router.GET("/metrics/series", handlers.MetricsSeries)
 			router.GET("/healthz", handlers.Health)
+			router.GET("/admin/ai/usage", handlers.ApiAdminAIUsage)
 
 			port := os.Getenv("PORT")
 			if port == "" {
 				port = "8080"
 			}
 			dsn := os.Getenv("MYSQL_DSN")
+			var metricsStore metrics.Store = metrics.NewMemoryStore()
 			if dsn != "" {
 				ok := false
 				for i := 0; i < 60; i++ {
 					if db, err := metrics.SetupDB(dsn); err == nil && db != nil {
-						metrics.Init(db)
-						log.Printf("metrics persistence enabled")
+						// With METRICS_DSN set, the metrics store is opened below.
+						if os.Getenv("METRICS_DSN") == "" {
+							if s, err := metrics.NewMySQLStore(db); err != nil {
+								log.Printf("metrics setup err: %v", err)
+							} else {
+								metricsStore = s
+								log.Printf("metrics persistence enabled")
+							}
+						}
+						if err := storage.Init(db); err != nil {
+							log.Printf("resume storage setup err: %v", err)
+						} else {
+							log.Printf("resume storage enabled")
+						}
+						if err := conversations.Init(db); err != nil {
+							log.Printf("ai conversations setup err: %v", err)
+						}
+						if err := ai.InitCache(db); err != nil {
+							log.Printf("ai cache setup err: %v", err)
+						}
+						if err := usage.Init(db); err != nil {
+							log.Printf("ai usage setup err: %v", err)
+						} else {
+							log.Printf("ai usage persistence enabled")
+						}
 						ok = true
 						break
 					} else if err != nil {
@@ -88,6 +139,15 @@ func main() {
 					panic("database not ready")
 				}
 			}
+			if v := os.Getenv("METRICS_DSN"); v != "" {
+				if s, err := metrics.Open(v); err != nil {
+					log.Printf("metrics store err: %v", err)
+				} else {
+					metricsStore = s
+					log.Printf("metrics store: %s", strings.SplitN(v, ":", 2)[0])
+				}
+			}
+			metrics.Init(metricsStore)
 			srv := &http.Server{Addr: ":" + port, Handler: router}
 			go func() {
 				if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -103,6 +163,13 @@ func main() {
 			if err := srv.Shutdown(ctx); err != nil {
 				log.Printf("server shutdown error: %v", err)
 			}
+			// Open streams can hold Shutdown until ctx expires, so the final
+			// flush gets a deadline of its own.
+			flushCtx, flushCancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer flushCancel()
+			if err := metrics.Close(flushCtx); err != nil {
+				log.Printf("metrics final flush error: %v", err)
+			}
 		}()
 	}
 }
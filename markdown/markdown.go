@@ -0,0 +1,394 @@
+// Package markdown serialises a resume into a stable, diff-friendly Markdown
+// layout and parses it back.
+//
+// Every heading line starts with '#', so body text lines that start with '#'
+// or '\' are escaped with a leading backslash. Body lines containing a
+// carriage return, as text from browser textareas does (CRLF), are written
+// as a backslash followed by the Go-quoted line. Single-line values are quoted
+// with Go syntax when they would not survive a round trip verbatim (newlines,
+// surrounding spaces, a leading quote).
+package markdown
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dongzhiwei-git/resume/models"
+)
+
+const (
+	headSummary    = "个人简介"
+	headSkills     = "专业技能"
+	headExperience = "工作经历"
+	headProjects   = "项目经历"
+	headEducation  = "教育背景"
+	headCerts      = "资格证书"
+	headAwards     = "荣誉奖项"
+	headCustom     = "自定义栏目"
+	headConfig     = "样式配置"
+
+	keyEmail     = "邮箱"
+	keyPhone     = "电话"
+	keyAvatar    = "头像"
+	keyCompany   = "公司"
+	keyDate      = "时间"
+	keySchool    = "学校"
+	keyRole      = "角色"
+	keyURL       = "链接"
+	keyIssuer    = "颁发机构"
+	keyTemplate  = "模板"
+	keyColor     = "颜色"
+	keyFont      = "字体"
+	keyFontSize  = "字号"
+	keyPaperSize = "纸张"
+	keyOrder     = "栏目顺序"
+	keySubtitle  = "副标题"
+)
+
+type field struct {
+	key string
+	val string
+}
+
+// Write renders r as Markdown. Sections are always emitted in the same order,
+// even when empty, so diffs between versions stay small.
+func Write(w io.Writer, r models.Resume) error {
+	b := &bytes.Buffer{}
+	heading(b, 1, r.Name)
+	meta(b, field{keyEmail, r.Email}, field{keyPhone, r.Phone}, field{keyAvatar, r.Avatar})
+
+	heading(b, 2, headSummary)
+	b.WriteString("\n")
+	text(b, r.Summary)
+
+	heading(b, 2, headSkills)
+	b.WriteString("\n")
+	for _, g := range r.Skills {
+		heading(b, 3, g.Category)
+		skillItems(b, g.Items)
+	}
+
+	heading(b, 2, headExperience)
+	b.WriteString("\n")
+	for _, e := range r.Experience {
+		heading(b, 3, e.Title)
+		meta(b, field{keyCompany, e.Company}, field{keyDate, e.Date})
+		text(b, e.Description)
+	}
+
+	heading(b, 2, headProjects)
+	b.WriteString("\n")
+	for _, p := range r.Projects {
+		heading(b, 3, p.Name)
+		meta(b, field{keyRole, p.Role}, field{keyDate, p.Date}, field{keyURL, p.URL})
+		text(b, p.Description)
+	}
+
+	heading(b, 2, headEducation)
+	b.WriteString("\n")
+	for _, e := range r.Education {
+		heading(b, 3, e.Degree)
+		meta(b, field{keySchool, e.School}, field{keyDate, e.Date})
+	}
+
+	heading(b, 2, headCerts)
+	b.WriteString("\n")
+	for _, c := range r.Certifications {
+		heading(b, 3, c.Name)
+		meta(b, field{keyIssuer, c.Issuer}, field{keyDate, c.Date})
+	}
+
+	heading(b, 2, headAwards)
+	b.WriteString("\n")
+	for _, a := range r.Awards {
+		heading(b, 3, a.Title)
+		meta(b, field{keyIssuer, a.Issuer}, field{keyDate, a.Date})
+		text(b, a.Description)
+	}
+
+	heading(b, 2, headCustom)
+	b.WriteString("\n")
+	for _, cs := range r.Custom {
+		heading(b, 3, cs.Title)
+		b.WriteString("\n")
+		for _, it := range cs.Items {
+			heading(b, 4, it.Heading)
+			meta(b, field{keySubtitle, it.Subtitle}, field{keyDate, it.Date})
+			text(b, it.Body)
+		}
+	}
+
+	heading(b, 2, headConfig)
+	meta(b,
+		field{keyTemplate, r.Config.Template},
+		field{keyColor, r.Config.Color},
+		field{keyFont, r.Config.Font},
+		field{keyFontSize, r.Config.FontSize},
+		field{keyPaperSize, r.Config.PaperSize},
+		field{keyOrder, strings.Join(r.Config.SectionOrder, ",")},
+	)
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func heading(b *bytes.Buffer, level int, title string) {
+	b.WriteString(strings.Repeat("#", level))
+	if title != "" {
+		b.WriteString(" " + quote(title))
+	}
+	b.WriteString("\n")
+}
+
+func meta(b *bytes.Buffer, fields ...field) {
+	b.WriteString("\n")
+	for _, f := range fields {
+		b.WriteString("- " + f.key + ":")
+		if f.val != "" {
+			b.WriteString(" " + quote(f.val))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+}
+
+// skillItems writes one "- name: level" line per item, in order. Names that
+// would be ambiguous with the separator are quoted.
+func skillItems(b *bytes.Buffer, items []models.SkillItem) {
+	b.WriteString("\n")
+	for _, it := range items {
+		name := quote(it.Name)
+		if name == "" || strings.Contains(name, ": ") || strings.HasSuffix(name, ":") {
+			name = strconv.Quote(it.Name)
+		}
+		b.WriteString("- " + name)
+		if it.Level != "" {
+			b.WriteString(": " + quote(it.Level))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+}
+
+func text(b *bytes.Buffer, s string) {
+	if s == "" {
+		return
+	}
+	for _, line := range strings.Split(s, "\n") {
+		if strings.Contains(line, "\r") {
+			line = `\` + strconv.Quote(line)
+		} else if strings.HasPrefix(line, "#") || strings.HasPrefix(line, `\`) {
+			line = `\` + line
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n")
+}
+
+func quote(s string) string {
+	if s != strings.TrimSpace(s) || strings.ContainsAny(s, "\r\n") || strings.HasPrefix(s, `"`) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func unquote(s string) (string, error) {
+	if strings.HasPrefix(s, `"`) {
+		return strconv.Unquote(s)
+	}
+	return s, nil
+}
+
+type block struct {
+	level int
+	title string
+	line  int
+	body  []string
+}
+
+// Parse reads a document produced by Write. Hand edits are tolerated as long
+// as headings and "- key: value" lines keep their shape.
+func Parse(rd io.Reader) (models.Resume, error) {
+	var r models.Resume
+	blocks, err := split(rd)
+	if err != nil {
+		return r, err
+	}
+	if len(blocks) == 0 || blocks[0].level != 1 {
+		return r, fmt.Errorf("markdown: missing '# name' heading")
+	}
+
+	section := ""
+	for _, bl := range blocks {
+		title, err := unquote(bl.title)
+		if err != nil {
+			return r, fmt.Errorf("markdown: line %d: %v", bl.line, err)
+		}
+		if bl.level == 2 {
+			section = title
+		}
+		if bl.level == 3 && section == headSkills {
+			items, err := parseSkillItems(bl)
+			if err != nil {
+				return r, err
+			}
+			r.Skills = append(r.Skills, models.SkillGroup{Category: title, Items: items})
+			continue
+		}
+		fields, body, err := parseBody(bl, bl.level != 2 || section == headConfig)
+		if err != nil {
+			return r, err
+		}
+		switch {
+		case bl.level == 1:
+			r.Name = title
+			r.Email, r.Phone, r.Avatar = fields[keyEmail], fields[keyPhone], fields[keyAvatar]
+		case bl.level == 2 && section == headSummary:
+			r.Summary = body
+		case bl.level == 2 && section == headConfig:
+			r.Config = models.ThemeConfig{
+				Template:  fields[keyTemplate],
+				Color:     fields[keyColor],
+				Font:      fields[keyFont],
+				FontSize:  fields[keyFontSize],
+				PaperSize: fields[keyPaperSize],
+			}
+			if order := fields[keyOrder]; order != "" {
+				r.Config.SectionOrder = strings.Split(order, ",")
+			}
+		case bl.level == 3 && section == headExperience:
+			r.Experience = append(r.Experience, models.Exp{Title: title, Company: fields[keyCompany], Date: fields[keyDate], Description: body})
+		case bl.level == 3 && section == headEducation:
+			r.Education = append(r.Education, models.Edu{Degree: title, School: fields[keySchool], Date: fields[keyDate]})
+		case bl.level == 3 && section == headProjects:
+			r.Projects = append(r.Projects, models.Project{Name: title, Role: fields[keyRole], Date: fields[keyDate], URL: fields[keyURL], Description: body})
+		case bl.level == 3 && section == headCerts:
+			r.Certifications = append(r.Certifications, models.Certification{Name: title, Issuer: fields[keyIssuer], Date: fields[keyDate]})
+		case bl.level == 3 && section == headCustom:
+			r.Custom = append(r.Custom, models.CustomSection{Title: title})
+		case bl.level == 4 && section == headCustom:
+			if len(r.Custom) == 0 {
+				r.Custom = append(r.Custom, models.CustomSection{})
+			}
+			cs := &r.Custom[len(r.Custom)-1]
+			cs.Items = append(cs.Items, models.CustomItem{Heading: title, Subtitle: fields[keySubtitle], Date: fields[keyDate], Body: body})
+		case bl.level == 3 && section == headAwards:
+			r.Awards = append(r.Awards, models.Award{Title: title, Issuer: fields[keyIssuer], Date: fields[keyDate], Description: body})
+		}
+	}
+	return r, nil
+}
+
+func split(rd io.Reader) ([]block, error) {
+	var blocks []block
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(make([]byte, 64*1024), 4<<20)
+	n := 0
+	for sc.Scan() {
+		n++
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if strings.HasPrefix(line, "#") {
+			level := len(line) - len(strings.TrimLeft(line, "#"))
+			if level <= 4 && (len(line) == level || line[level] == ' ') {
+				blocks = append(blocks, block{level: level, title: strings.TrimPrefix(line[level:], " "), line: n})
+				continue
+			}
+		}
+		if len(blocks) == 0 {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			return nil, fmt.Errorf("markdown: line %d: text before first heading", n)
+		}
+		last := &blocks[len(blocks)-1]
+		last.body = append(last.body, line)
+	}
+	return blocks, sc.Err()
+}
+
+// parseBody splits a block body into its "- key: value" list and free text.
+// Layout: blank line, list (only when withMeta), blank line, text, blank line.
+func parseBody(bl block, withMeta bool) (map[string]string, string, error) {
+	fields := map[string]string{}
+	lines := bl.body
+	if len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	i := 0
+	for ; withMeta && i < len(lines); i++ {
+		item := strings.TrimPrefix(lines[i], "- ")
+		if item == lines[i] {
+			break
+		}
+		k, v, ok := strings.Cut(item, ": ")
+		if !ok {
+			if !strings.HasSuffix(item, ":") {
+				break
+			}
+			k, v = strings.TrimSuffix(item, ":"), ""
+		}
+		val, err := unquote(v)
+		if err != nil {
+			return nil, "", fmt.Errorf("markdown: line %d: %v", bl.line+2+i, err)
+		}
+		fields[k] = val
+	}
+	if withMeta && i < len(lines) && lines[i] == "" {
+		i++
+	}
+	rest := lines[i:]
+	first := bl.line + 1 + len(bl.body) - len(rest) // line number of rest[0]
+	if n := len(rest); n > 0 && rest[n-1] == "" {
+		rest = rest[:n-1]
+	}
+	for j, line := range rest {
+		if strings.HasPrefix(line, `\"`) {
+			s, err := strconv.Unquote(line[1:])
+			if err != nil {
+				return nil, "", fmt.Errorf("markdown: line %d: %v", first+j, err)
+			}
+			rest[j] = s
+		} else if strings.HasPrefix(line, `\`) {
+			rest[j] = line[1:]
+		}
+	}
+	return fields, strings.Join(rest, "\n"), nil
+}
+
+// parseSkillItems reads the ordered item list written by skillItems.
+func parseSkillItems(bl block) ([]models.SkillItem, error) {
+	var items []models.SkillItem
+	for i, line := range bl.body {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		item := strings.TrimPrefix(line, "- ")
+		if item == line {
+			return nil, fmt.Errorf("markdown: line %d: expected '- skill: level'", bl.line+1+i)
+		}
+		var name, rest string
+		if strings.HasPrefix(item, `"`) {
+			q, err := strconv.QuotedPrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("markdown: line %d: %v", bl.line+1+i, err)
+			}
+			name, _ = strconv.Unquote(q)
+			rest = item[len(q):]
+			if rest != "" && !strings.HasPrefix(rest, ": ") {
+				return nil, fmt.Errorf("markdown: line %d: unexpected text after skill name", bl.line+1+i)
+			}
+			rest = strings.TrimPrefix(rest, ": ")
+		} else {
+			name, rest, _ = strings.Cut(item, ": ")
+		}
+		level, err := unquote(rest)
+		if err != nil {
+			return nil, fmt.Errorf("markdown: line %d: %v", bl.line+1+i, err)
+		}
+		items = append(items, models.SkillItem{Name: name, Level: level})
+	}
+	return items, nil
+}
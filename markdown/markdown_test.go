@@ -0,0 +1,85 @@
+package markdown
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dongzhiwei-git/resume/models"
+)
+
+func roundTrip(t *testing.T, r models.Resume) models.Resume {
+	t.Helper()
+	var b bytes.Buffer
+	if err := Write(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(&b)
+	if err != nil {
+		t.Fatalf("Parse: %v\n%s", err, b.String())
+	}
+	return got
+}
+
+func TestRoundTrip(t *testing.T) {
+	tricky := models.Resume{
+		Name:    " 张三 ",
+		Email:   "z@example.com",
+		Phone:   `"138"`,
+		Summary: "第一行\r\n# 不是标题\r\n\\反斜杠\r\n\r\n\"引号\"\r\n",
+		Skills: []models.SkillGroup{
+			{Category: "语言", Items: []models.SkillItem{{Name: "Go", Level: "精通"}, {Name: "C: 系统", Level: ""}, {Name: "SQL:"}}},
+		},
+		Experience: []models.Exp{
+			{Title: "工程师", Company: "某公司", Date: "2020-06 - 至今", Description: "a\r\nb\rc\n#d\n\\e"},
+			{Title: "实习", Company: "", Date: "", Description: ""},
+		},
+		Education:      []models.Edu{{Degree: "本科", School: "某大学", Date: "2016 - 2020"}},
+		Projects:       []models.Project{{Name: "项目", Role: "负责人", Date: "2021", URL: "https://example.com", Description: "做了很多事\n\n还有更多"}},
+		Certifications: []models.Certification{{Name: "证书", Issuer: "机构", Date: "2019"}},
+		Awards:         []models.Award{{Title: "奖项", Issuer: "机构", Date: "2018", Description: "一等奖"}},
+		Custom: []models.CustomSection{{Title: "志愿者", Items: []models.CustomItem{
+			{Heading: "支教", Subtitle: "某地", Date: "2015", Body: "教书\r\n育人"},
+		}}},
+		Config: models.ThemeConfig{Template: "modern", Color: "#123456", Font: "serif", FontSize: "small", PaperSize: "letter",
+			SectionOrder: []string{"summary", "custom.0", "experience"}},
+	}
+	for name, r := range map[string]models.Resume{"demo": models.GetDemoResume(), "tricky": tricky} {
+		t.Run(name, func(t *testing.T) {
+			if got := roundTrip(t, r); !reflect.DeepEqual(got, r) {
+				t.Errorf("round trip differs:\ngot  %#v\nwant %#v", got, r)
+			}
+		})
+	}
+}
+
+// A document saved with CRLF line endings reads like one with LF.
+func TestParseCRLF(t *testing.T) {
+	var b bytes.Buffer
+	r := models.GetDemoResume()
+	if err := Write(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(strings.NewReader(strings.ReplaceAll(b.String(), "\n", "\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, r) {
+		t.Errorf("CRLF document differs:\ngot  %#v\nwant %#v", got, r)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct{ doc, want string }{
+		{"text\n# 张三\n", "line 1: text before first heading"},
+		{"## 个人简介\n", "missing '# name' heading"},
+		{"# 张三\n\n## 个人简介\n\n\\\"unterminated\n", "line 5:"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.doc))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q): got %v, want %q", tt.doc, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// hllPrecision gives 2^12 registers: 4 KiB per sketch and a standard error
+// of about 1.6%.
+const hllPrecision = 12
+
+const hllRegisters = 1 << hllPrecision
+
+// HLL is a HyperLogLog sketch estimating the number of distinct 64-bit
+// hashes added to it. Sketches merge losslessly, so instances can each
+// count their own visitors and combine them in the database.
+type HLL struct {
+	reg [hllRegisters]uint8
+}
+
+// Add records a uniformly distributed hash.
+func (h *HLL) Add(x uint64) {
+	i := x >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > h.reg[i] {
+		h.reg[i] = rank
+	}
+}
+
+// Merge folds o into h; the result estimates the union of both.
+func (h *HLL) Merge(o *HLL) {
+	for i, r := range o.reg {
+		if r > h.reg[i] {
+			h.reg[i] = r
+		}
+	}
+}
+
+// Estimate returns the estimated number of distinct hashes, using linear
+// counting for small cardinalities.
+func (h *HLL) Estimate() int64 {
+	m := float64(hllRegisters)
+	sum, zeros := 0.0, 0
+	for _, r := range h.reg {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return int64(e + 0.5)
+}
+
+// Bytes returns the registers for storage.
+func (h *HLL) Bytes() []byte {
+	return append([]byte(nil), h.reg[:]...)
+}
+
+// ParseHLL restores a sketch stored with Bytes.
+func ParseHLL(b []byte) (*HLL, error) {
+	if len(b) != hllRegisters {
+		return nil, errors.New("metrics: bad sketch size")
+	}
+	h := &HLL{}
+	copy(h.reg[:], b)
+	return h, nil
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// visitorHash hashes like observeVisitor does.
+func visitorHash(i int) uint64 {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("salt10.0.%d.%d\x00Mozilla", i/256, i%256)))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+func TestHLLAccuracy(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 100, 1000, 5000, 20000, 100000} {
+		h := &HLL{}
+		for i := 0; i < n; i++ {
+			h.Add(visitorHash(i))
+			h.Add(visitorHash(i)) // repeats do not count
+		}
+		got := h.Estimate()
+		// 3 standard errors, and exact enough for tiny counts.
+		if tol := math.Max(3*0.016*float64(n), 1); math.Abs(float64(got)-float64(n)) > tol {
+			t.Errorf("n=%d: estimate %d", n, got)
+		}
+	}
+}
+
+func TestHLLMerge(t *testing.T) {
+	a, b, union := &HLL{}, &HLL{}, &HLL{}
+	for i := 0; i < 30000; i++ {
+		x := visitorHash(i)
+		if i < 20000 {
+			a.Add(x)
+		}
+		if i >= 10000 {
+			b.Add(x)
+		}
+		union.Add(x)
+	}
+	a.Merge(b)
+	if *a != *union {
+		t.Fatal("merge differs from the sketch of the union")
+	}
+	a.Merge(b)
+	if *a != *union {
+		t.Fatal("merging twice changed the sketch")
+	}
+
+	p, err := ParseHLL(a.Bytes())
+	if err != nil || *p != *a {
+		t.Fatalf("Bytes/ParseHLL round trip: %v", err)
+	}
+	if _, err := ParseHLL([]byte{1, 2, 3}); err == nil {
+		t.Error("ParseHLL accepted a short sketch")
+	}
+}
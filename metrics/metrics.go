@@ -1,54 +1,346 @@
 package metrics
 
 import (
-	"database/sql"
+	"context"
 	"log"
+	"os"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
+// Event types of the daily series.
+const (
+	EventVisit      = "visit"       // a page view
+	EventPreview    = "preview"     // a full preview page
+	EventPDF        = "pdf"         // a document export: PDF or DOCX download, or printing the preview
+	EventImport     = "import"      // an imported resume file
+	EventAIGenerate = "ai_generate" // a resume generated by the AI assistant
+	EventAIRevise   = "ai_revise"   // a resume revised or tailored by the AI assistant
+	EventBot        = "bot"         // a page view by a crawler or probe, not counted as a visit
+)
+
+// Events lists every counted event type.
+var Events = []string{EventVisit, EventPreview, EventPDF, EventImport, EventAIGenerate, EventAIRevise, EventBot}
+
+// EventUniqueVisitor is the series of estimated unique visitors per day. It
+// is not counted with Inc but derived from the visitor sketches.
+const EventUniqueVisitor = "unique_visitor"
+
+// SeriesEvents lists every series Series can return.
+var SeriesEvents = append(append([]string(nil), Events...), EventUniqueVisitor)
+
+// isGenerate reports whether event counts towards generates: every event
+// but visits and bots.
+func isGenerate(event string) bool {
+	return event != EventVisit && event != EventBot
+}
+
+// visits and generates count this process's events since it started, for
+// the Prometheus counters. They are not seeded from the store, which
+// instances share, so that sums across instances do not double count.
 var visits int64
 var generates int64
-var db *sql.DB
 
-func useDB() bool { return db != nil }
+// store holds the series and the visitor sketches. Init replaces it.
+var store Store = NewMemoryStore()
 
-func Init(d *sql.DB) {
-	db = d
-	var v, g int64
-	if err := db.QueryRow("SELECT visits, generates FROM metrics_counters WHERE id=1").Scan(&v, &g); err == nil {
-		atomic.StoreInt64(&visits, v)
-		atomic.StoreInt64(&generates, g)
-	} else {
+// dayEvent keys the counts of one event on one day (YYYY-MM-DD, local time).
+type dayEvent struct {
+	day, event string
+}
+
+var (
+	countsMu sync.Mutex
+	// pending holds the counts not yet written to the store; requests only
+	// touch this map and a background flusher moves it to the store.
+	pending = map[dayEvent]int64{}
+	// flushing holds the batch being written, which still counts until the
+	// cached totals include it.
+	flushing map[dayEvent]int64
+	// stored caches the store's totals per event, refreshed on every flush so
+	// that Snapshot never waits for the store.
+	stored = map[string]int64{}
+)
+
+var (
+	flushMu   sync.Mutex // serialises flushes
+	flushStop chan struct{}
+	flushDone chan struct{}
+)
+
+// flushRetries bounds the attempts of one flush. Counts that still fail are
+// kept for the next one.
+const flushRetries = 3
+
+// Init makes s the store, loads the cached totals from it and starts
+// flushing counts every METRICS_FLUSH_INTERVAL (default 5s). Call Close on
+// shutdown.
+func Init(s Store) {
+	stopFlusher()
+	store = s
+	countsMu.Lock()
+	stored = map[string]int64{}
+	countsMu.Unlock()
+	if err := refreshTotals(context.Background()); err != nil {
 		log.Printf("metrics init load err: %v", err)
 	}
+	flushStop, flushDone = make(chan struct{}), make(chan struct{})
+	go flushLoop(flushInterval(), flushStop, flushDone)
 }
 
-func IncVisit() {
-	atomic.AddInt64(&visits, 1)
-	if useDB() {
-		if _, err := db.Exec("UPDATE metrics_counters SET visits=visits+1, updated_at=NOW() WHERE id=1"); err != nil {
-			log.Printf("metrics visit db err: %v", err)
+func flushInterval() time.Duration {
+	if v := os.Getenv("METRICS_FLUSH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err == nil && d > 0 {
+			return d
 		}
+		log.Printf("metrics: bad METRICS_FLUSH_INTERVAL %q", v)
 	}
+	return 5 * time.Second
 }
 
-func IncGenerate() {
-	atomic.AddInt64(&generates, 1)
-	if useDB() {
-		if _, err := db.Exec("UPDATE metrics_counters SET generates=generates+1, updated_at=NOW() WHERE id=1"); err != nil {
-			log.Printf("metrics generate db err: %v", err)
+func flushLoop(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			if err := flushAll(context.Background()); err != nil {
+				log.Printf("metrics flush err: %v", err)
+			}
 		}
 	}
 }
 
+func stopFlusher() {
+	if flushStop != nil {
+		close(flushStop)
+		<-flushDone
+		flushStop, flushDone = nil, nil
+	}
+}
+
+// refreshTotals reloads the cached totals from the store.
+func refreshTotals(ctx context.Context) error {
+	t, err := store.Totals(ctx)
+	if err != nil {
+		return err
+	}
+	countsMu.Lock()
+	stored = t
+	countsMu.Unlock()
+	return nil
+}
+
+// flush writes the pending counts in one batch and refreshes the cached
+// totals, picking up other instances' counts. On failure the counts are
+// added back, so nothing is lost, only delayed.
+func flush(ctx context.Context) error {
+	flushMu.Lock()
+	defer flushMu.Unlock()
+	countsMu.Lock()
+	batch := pending
+	pending = map[dayEvent]int64{}
+	flushing = batch
+	countsMu.Unlock()
+	if len(batch) == 0 {
+		return refreshTotals(ctx)
+	}
+	counts := make([]Count, 0, len(batch))
+	for k, n := range batch {
+		counts = append(counts, Count{Day: k.day, Event: k.event, N: n})
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = store.AddCounts(ctx, counts)
+		if err == nil {
+			break
+		}
+		if attempt == flushRetries || !sleep(ctx, time.Duration(attempt)*200*time.Millisecond) {
+			break
+		}
+	}
+	if err != nil {
+		countsMu.Lock()
+		for k, n := range batch {
+			pending[k] += n
+		}
+		flushing = nil
+		countsMu.Unlock()
+		return err
+	}
+	t, err := store.Totals(ctx)
+	countsMu.Lock()
+	if err == nil {
+		stored = t
+	} else {
+		// Written but not reloaded: count the batch until the next refresh.
+		for k, n := range batch {
+			stored[k.event] += n
+		}
+	}
+	flushing = nil
+	countsMu.Unlock()
+	return err
+}
+
+// sleep waits for d and reports whether ctx is still live.
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Close stops the background flusher, writes the remaining counts and
+// visitor sketches and closes the store. Call it after the HTTP server has
+// shut down, so that no request can count after the final flush.
+func Close(ctx context.Context) error {
+	if flushStop == nil {
+		return nil
+	}
+	stopFlusher()
+	err := flushAll(ctx)
+	if cerr := store.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// flushAll writes the pending counts and the visitor sketches, returning
+// the first error.
+func flushAll(ctx context.Context) error {
+	err := flush(ctx)
+	if serr := flushSketches(ctx); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// Inc counts one event of type event today.
+func Inc(event string) {
+	if event == EventVisit {
+		atomic.AddInt64(&visits, 1)
+	} else if isGenerate(event) {
+		atomic.AddInt64(&generates, 1)
+	}
+	k := dayEvent{day: time.Now().Format(dayLayout), event: event}
+	countsMu.Lock()
+	pending[k]++
+	countsMu.Unlock()
+}
+
+func IncVisit() { Inc(EventVisit) }
+
+// IncGenerate counts a document export, the generate of earlier versions.
+func IncGenerate() { Inc(EventPDF) }
+
+const dayLayout = "2006-01-02"
+
+// Snapshot returns the visit total and the total of all other events but
+// bots, summed over the series. With a shared store it covers every instance
+// as of the last flush, plus this one's unflushed counts. It does not touch
+// the store.
 func Snapshot() (int64, int64) {
-	if useDB() {
-		var v, g int64
-		if err := db.QueryRow("SELECT visits, generates FROM metrics_counters WHERE id=1").Scan(&v, &g); err == nil {
-			return v, g
+	return totals()
+}
+
+func totals() (v, g int64) {
+	add := func(event string, n int64) {
+		if event == EventVisit {
+			v += n
+		} else if isGenerate(event) {
+			g += n
 		}
 	}
-	return atomic.LoadInt64(&visits), atomic.LoadInt64(&generates)
+	countsMu.Lock()
+	defer countsMu.Unlock()
+	for event, n := range stored {
+		add(event, n)
+	}
+	for _, m := range []map[dayEvent]int64{flushing, pending} {
+		for k, n := range m {
+			add(k.event, n)
+		}
+	}
+	return v, g
+}
+
+// Point is the count of one event on one day.
+type Point struct {
+	Day   string `json:"day"`
+	Count int64  `json:"count"`
 }
 
-func Ready() bool { return db != nil }
+// Series returns, for each of events, one point per day from from to to
+// inclusive (both YYYY-MM-DD), days without events counting zero. Events
+// may include EventUniqueVisitor.
+func Series(from, to string, events []string) (map[string][]Point, error) {
+	counts := map[dayEvent]int64{}
+	stored, err := store.Counts(context.Background(), from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range stored {
+		counts[dayEvent{c.Day, c.Event}] += c.N
+	}
+	countsMu.Lock()
+	for _, m := range []map[dayEvent]int64{flushing, pending} {
+		for k, n := range m {
+			if k.day >= from && k.day <= to {
+				counts[k] += n
+			}
+		}
+	}
+	countsMu.Unlock()
+
+	start, err := time.ParseInLocation(dayLayout, from, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.ParseInLocation(dayLayout, to, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	var days []string
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		days = append(days, d.Format(dayLayout))
+	}
+	out := map[string][]Point{}
+	for _, event := range events {
+		if event == EventUniqueVisitor {
+			if out[event], err = uniqueVisitors(from, to, days); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		points := make([]Point, 0, len(days))
+		for _, day := range days {
+			points = append(points, Point{Day: day, Count: counts[dayEvent{day, event}]})
+		}
+		out[event] = points
+	}
+	return out, nil
+}
+
+// KnownEvent reports whether event is one of SeriesEvents.
+func KnownEvent(event string) bool {
+	for _, e := range SeriesEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Ready reports whether the metrics outlive the process.
+func Ready() bool {
+	_, inMemory := store.(*MemoryStore)
+	return !inMemory
+}
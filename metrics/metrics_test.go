@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingStore counts Totals calls and can fail writes.
+type countingStore struct {
+	*MemoryStore
+	totalsCalls int
+	failWrites  bool
+}
+
+func (s *countingStore) Totals(ctx context.Context) (map[string]int64, error) {
+	s.totalsCalls++
+	return s.MemoryStore.Totals(ctx)
+}
+
+func (s *countingStore) AddCounts(ctx context.Context, counts []Count) error {
+	if s.failWrites {
+		return errors.New("down")
+	}
+	return s.MemoryStore.AddCounts(ctx, counts)
+}
+
+func initTestStore(t *testing.T, s Store) {
+	t.Helper()
+	t.Setenv("METRICS_FLUSH_INTERVAL", "1h")
+	countsMu.Lock()
+	pending = map[dayEvent]int64{}
+	countsMu.Unlock()
+	Init(s)
+	t.Cleanup(func() {
+		if err := Close(context.Background()); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+		Init(NewMemoryStore())
+		stopFlusher()
+	})
+}
+
+func TestSnapshotUsesCachedTotals(t *testing.T) {
+	s := &countingStore{MemoryStore: NewMemoryStore()}
+	if err := s.AddCounts(context.Background(), []Count{{"2026-01-01", EventVisit, 10}, {"2026-01-01", EventPDF, 4}, {"2026-01-01", EventBot, 9}}); err != nil {
+		t.Fatal(err)
+	}
+	initTestStore(t, s)
+	calls := s.totalsCalls
+
+	IncVisit()
+	Inc(EventAIGenerate)
+	Inc(EventBot)
+	for i := 0; i < 3; i++ {
+		if v, g := Snapshot(); v != 11 || g != 5 {
+			t.Fatalf("Snapshot: %d, %d; want 11, 5", v, g)
+		}
+	}
+	if s.totalsCalls != calls {
+		t.Errorf("Snapshot queried the store %d times", s.totalsCalls-calls)
+	}
+
+	// Another instance writes; a flush picks it up.
+	if err := s.MemoryStore.AddCounts(context.Background(), []Count{{"2026-01-02", EventVisit, 100}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if v, g := Snapshot(); v != 111 || g != 5 {
+		t.Errorf("after flush: %d, %d; want 111, 5", v, g)
+	}
+
+	// Failed writes stay pending and keep counting.
+	s.failWrites = true
+	IncVisit()
+	if err := flush(context.Background()); err == nil {
+		t.Fatal("flush should fail")
+	}
+	if v, _ := Snapshot(); v != 112 {
+		t.Errorf("after failed flush: %d; want 112", v)
+	}
+	s.failWrites = false
+}
+
+// slowWriteStore holds AddCounts until release is closed.
+type slowWriteStore struct {
+	*MemoryStore
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *slowWriteStore) AddCounts(ctx context.Context, counts []Count) error {
+	select {
+	case <-s.entered:
+	default:
+		close(s.entered)
+	}
+	<-s.release
+	return s.MemoryStore.AddCounts(ctx, counts)
+}
+
+// A batch being written still shows in Series, as it does in Snapshot.
+func TestSeriesCountsFlushingBatch(t *testing.T) {
+	s := &slowWriteStore{MemoryStore: NewMemoryStore(), entered: make(chan struct{}), release: make(chan struct{})}
+	initTestStore(t, s)
+	today := time.Now().Format(dayLayout)
+
+	IncVisit()
+	IncVisit()
+	done := make(chan error)
+	go func() { done <- flush(context.Background()) }()
+	<-s.entered
+	IncVisit()
+	series, err := Series(today, today, []string{EventVisit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := Snapshot(); len(series[EventVisit]) != 1 || series[EventVisit][0].Count != 3 || v != 3 {
+		t.Errorf("during flush: series %v, snapshot %d; want 3", series[EventVisit], v)
+	}
+	close(s.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if series, _ := Series(today, today, []string{EventVisit}); series[EventVisit][0].Count != 3 {
+		t.Errorf("after flush: %v", series[EventVisit])
+	}
+}
+
+// The Prometheus counters count this instance only, not the shared totals.
+func TestPrometheusCountsProcessOnly(t *testing.T) {
+	s := NewMemoryStore()
+	if err := s.AddCounts(context.Background(), []Count{{"2026-01-01", EventVisit, 100}, {"2026-01-01", EventPDF, 40}}); err != nil {
+		t.Fatal(err)
+	}
+	v0, g0 := atomic.LoadInt64(&visits), atomic.LoadInt64(&generates)
+	initTestStore(t, s)
+	IncVisit()
+	Inc(EventPDF)
+	Inc(EventBot)
+
+	var b bytes.Buffer
+	WritePrometheus(&b)
+	for _, want := range []string{
+		fmt.Sprintf("\nresume_visits_total %d\n", v0+1),
+		fmt.Sprintf("\nresume_generates_total %d\n", g0+1),
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("missing %q in\n%s", want, b.String())
+		}
+	}
+	if v, g := Snapshot(); v != 101 || g != 41 {
+		t.Errorf("Snapshot: %d, %d; want 101, 41", v, g)
+	}
+}
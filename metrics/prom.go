@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The process metrics below are exposed in the Prometheus text format by
+// WritePrometheus, together with the visit and generate counters. They are
+// kept in memory only and start from zero with every process.
+
+// latencyBuckets are the upper bounds, in seconds, of the latency
+// histograms. They reach further than the usual defaults because AI
+// streams and remote PDF renders can take a minute.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+var (
+	httpRequests = newFamily("http_requests_total", "counter",
+		"HTTP requests by method, route template and status.", "method", "route", "status")
+	httpDuration = newFamily("http_request_duration_seconds", "histogram",
+		"HTTP request latency by method, route template and status.", "method", "route", "status")
+	httpInFlight = newFamily("http_requests_in_flight", "gauge",
+		"HTTP requests being served, by method and route template.", "method", "route")
+	aiCalls = newFamily("ai_calls_total", "counter",
+		"Calls to the AI provider by provider, call kind and result.", "provider", "call", "result")
+	aiTokens = newFamily("ai_tokens_total", "counter",
+		"Tokens reported by the AI provider, by provider and token type.", "provider", "type")
+	pdfRenders = newFamily("pdf_renders_total", "counter",
+		"PDF renders by document and result.", "document", "result")
+	pdfDuration = newFamily("pdf_render_duration_seconds", "histogram",
+		"PDF render latency by document.", "document")
+	uploadBytes = newFamily("upload_bytes_total", "counter",
+		"Bytes of uploaded files by kind.", "kind")
+)
+
+var families = []*family{httpRequests, httpDuration, httpInFlight, aiCalls, aiTokens, pdfRenders, pdfDuration, uploadBytes}
+
+// family is one metric name with its series, keyed by label values.
+type family struct {
+	name, kind, help string
+	labels           []string
+	mu               sync.Mutex
+	series           map[string]*series
+}
+
+type series struct {
+	values []string
+	value  float64
+	counts []uint64 // per bucket, histograms only
+	sum    float64
+	count  uint64
+}
+
+func newFamily(name, kind, help string, labels ...string) *family {
+	return &family{name: name, kind: kind, help: help, labels: labels, series: map[string]*series{}}
+}
+
+// get returns the series for values; callers hold f.mu.
+func (f *family) get(values []string) *series {
+	key := strings.Join(values, "\x00")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: values}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(latencyBuckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) add(v float64, values ...string) {
+	f.mu.Lock()
+	f.get(values).value += v
+	f.mu.Unlock()
+}
+
+func (f *family) observe(v float64, values ...string) {
+	f.mu.Lock()
+	s := f.get(values)
+	for i, le := range latencyBuckets {
+		if v <= le {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+	f.mu.Unlock()
+}
+
+func (f *family) write(w io.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := f.series[k]
+		labels := labelPairs(f.labels, s.values)
+		if f.kind != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, braces(labels), formatFloat(s.value))
+			continue
+		}
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, braces(append(labels, `le="`+formatFloat(le)+`"`)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, braces(append(labels, `le="+Inf"`)), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, braces(labels), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, braces(labels), s.count)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labelPairs(names, values []string) []string {
+	pairs := make([]string, len(names), len(names)+1)
+	for i, n := range names {
+		pairs[i] = n + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return pairs
+}
+
+func braces(pairs []string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// WritePrometheus writes every metric in the Prometheus text format. The
+// visit and generate counters start at zero with the process, like the
+// others; the persistent totals are Snapshot's.
+func WritePrometheus(w io.Writer) {
+	fmt.Fprintf(w, "# HELP resume_visits_total Page visits counted by this instance since it started.\n# TYPE resume_visits_total counter\nresume_visits_total %d\n", atomic.LoadInt64(&visits))
+	fmt.Fprintf(w, "# HELP resume_generates_total Previews, exports, imports and AI resumes counted by this instance since it started.\n# TYPE resume_generates_total counter\nresume_generates_total %d\n", atomic.LoadInt64(&generates))
+	for _, f := range families {
+		f.write(w)
+	}
+}
+
+// Instrument is Gin middleware recording every request by method, route
+// template (c.FullPath(), so /r/:slug rather than each slug) and status.
+// Requests that match no route share the route label "unmatched".
+func Instrument(c *gin.Context) {
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	method := c.Request.Method
+	start := time.Now()
+	httpInFlight.add(1, method, route)
+	defer func() {
+		httpInFlight.add(-1, method, route)
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequests.add(1, method, route, status)
+		httpDuration.observe(time.Since(start).Seconds(), method, route, status)
+	}()
+	c.Next()
+}
+
+// ObserveAICall records one call to the AI provider: call is "chat",
+// "stream" or "json".
+func ObserveAICall(provider, call string, promptTokens, completionTokens int, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	aiCalls.add(1, provider, call, result)
+	if promptTokens > 0 {
+		aiTokens.add(float64(promptTokens), provider, "prompt")
+	}
+	if completionTokens > 0 {
+		aiTokens.add(float64(completionTokens), provider, "completion")
+	}
+}
+
+// ObservePDFRender records one PDF render of document ("resume" or
+// "cover_letter").
+func ObservePDFRender(document string, d time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	pdfRenders.add(1, document, result)
+	pdfDuration.observe(d.Seconds(), document)
+}
+
+// AddUploadBytes counts n bytes of an uploaded file of kind.
+func AddUploadBytes(kind string, n int64) {
+	uploadBytes.add(float64(n), kind)
+}
@@ -0,0 +1,293 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// dialect holds the statements that differ between SQL databases.
+type dialect struct {
+	schema []string
+	// upsertCounts adds to metrics_daily; %s stands for the "(?, ?, ?)" rows.
+	upsertCounts   string
+	selectCounts   string
+	insertSalt     string
+	lockSketch     string
+	upsertSketch   string
+	selectSketches string
+}
+
+// sqlStore is a Store on database/sql.
+type sqlStore struct {
+	db    *sql.DB
+	d     dialect
+	owned bool // Close closes db
+}
+
+func newSQLStore(db *sql.DB, d dialect) (*sqlStore, error) {
+	for _, stmt := range d.schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return &sqlStore{db: db, d: d}, nil
+}
+
+func (s *sqlStore) AddCounts(ctx context.Context, counts []Count) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	rows := make([]string, 0, len(counts))
+	args := make([]any, 0, 3*len(counts))
+	for _, c := range counts {
+		rows = append(rows, "(?, ?, ?)")
+		args = append(args, c.Day, c.Event, c.N)
+	}
+	_, err := s.db.ExecContext(ctx, strings.Replace(s.d.upsertCounts, "%s", strings.Join(rows, ", "), 1), args...)
+	return err
+}
+
+func (s *sqlStore) Totals(ctx context.Context) (map[string]int64, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT event, SUM(count) FROM metrics_daily GROUP BY event")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	totals := map[string]int64{}
+	for rows.Next() {
+		var event string
+		var n int64
+		if err := rows.Scan(&event, &n); err != nil {
+			return nil, err
+		}
+		totals[event] = n
+	}
+	return totals, rows.Err()
+}
+
+func (s *sqlStore) Counts(ctx context.Context, from, to string) ([]Count, error) {
+	rows, err := s.db.QueryContext(ctx, s.d.selectCounts, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var counts []Count
+	for rows.Next() {
+		var c Count
+		if err := rows.Scan(&c.Day, &c.Event, &c.N); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+func (s *sqlStore) Salt(ctx context.Context, day string, candidate []byte) ([]byte, error) {
+	if _, err := s.db.ExecContext(ctx, s.d.insertSalt, day, candidate); err != nil {
+		return nil, err
+	}
+	var salt []byte
+	if err := s.db.QueryRowContext(ctx, "SELECT salt FROM metrics_visitor_salts WHERE day=?", day).Scan(&salt); err != nil {
+		return nil, err
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM metrics_visitor_salts WHERE day < ?", day); err != nil {
+		log.Printf("metrics salt db err: %v", err)
+	}
+	return salt, nil
+}
+
+func (s *sqlStore) MergeSketch(ctx context.Context, day string, h *HLL) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	merged := *h
+	var stored []byte
+	err = tx.QueryRowContext(ctx, s.d.lockSketch, day).Scan(&stored)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil {
+		if old, perr := ParseHLL(stored); perr == nil {
+			merged.Merge(old)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, s.d.upsertSketch, day, merged.Bytes()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) Sketches(ctx context.Context, from, to string) (map[string]*HLL, error) {
+	rows, err := s.db.QueryContext(ctx, s.d.selectSketches, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]*HLL{}
+	for rows.Next() {
+		var day string
+		var b []byte
+		if err := rows.Scan(&day, &b); err != nil {
+			return nil, err
+		}
+		if h, err := ParseHLL(b); err == nil {
+			out[day] = h
+		}
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) Close() error {
+	if s.owned {
+		return s.db.Close()
+	}
+	return nil
+}
+
+var mysqlDialect = dialect{
+	schema: []string{`
+        CREATE TABLE IF NOT EXISTS metrics_daily (
+            day DATE NOT NULL,
+            event VARCHAR(32) NOT NULL,
+            count BIGINT NOT NULL DEFAULT 0,
+            PRIMARY KEY (day, event)
+        ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+    `, `
+        CREATE TABLE IF NOT EXISTS metrics_visitors (
+            day DATE PRIMARY KEY,
+            registers BLOB NOT NULL
+        ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+    `, `
+        CREATE TABLE IF NOT EXISTS metrics_visitor_salts (
+            day DATE PRIMARY KEY,
+            salt BINARY(32) NOT NULL
+        ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+    `},
+	upsertCounts:   "INSERT INTO metrics_daily (day, event, count) VALUES %s ON DUPLICATE KEY UPDATE count=count+VALUES(count)",
+	selectCounts:   "SELECT DATE_FORMAT(day, '%Y-%m-%d'), event, count FROM metrics_daily WHERE day BETWEEN ? AND ?",
+	insertSalt:     "INSERT IGNORE INTO metrics_visitor_salts (day, salt) VALUES (?, ?)",
+	lockSketch:     "SELECT registers FROM metrics_visitors WHERE day=? FOR UPDATE",
+	upsertSketch:   "INSERT INTO metrics_visitors (day, registers) VALUES (?, ?) ON DUPLICATE KEY UPDATE registers=VALUES(registers)",
+	selectSketches: "SELECT DATE_FORMAT(day, '%Y-%m-%d'), registers FROM metrics_visitors WHERE day BETWEEN ? AND ?",
+}
+
+// MySQLStore keeps the metrics in MySQL, shared by every instance.
+type MySQLStore struct {
+	*sqlStore
+}
+
+// NewMySQLStore creates the metrics tables in db and migrates the lifetime
+// counters of metrics_counters, if SetupDB created it, on first start. A
+// failed migration fails the store, so that nothing is written to
+// metrics_daily before the next start can retry it.
+func NewMySQLStore(db *sql.DB) (*MySQLStore, error) {
+	s, err := newSQLStore(db, mysqlDialect)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrateCounters(db); err != nil {
+		return nil, fmt.Errorf("migrate metrics_counters: %w", err)
+	}
+	return &MySQLStore{s}, nil
+}
+
+// migrateCounters copies the lifetime totals of metrics_counters into
+// metrics_daily, dated the day they were last updated, unless the series
+// already has rows. Earlier versions counted downloads and prints as
+// generates; they become pdf events. The lock on the counters row keeps
+// instances starting together from migrating twice.
+func migrateCounters(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var tables int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM information_schema.TABLES
+        WHERE TABLE_SCHEMA=DATABASE() AND TABLE_NAME='metrics_counters'`).Scan(&tables)
+	if err != nil || tables == 0 {
+		return err
+	}
+	var v, g int64
+	var day string
+	err = tx.QueryRow("SELECT visits, generates, DATE_FORMAT(COALESCE(updated_at, NOW()), '%Y-%m-%d') FROM metrics_counters WHERE id=1 FOR UPDATE").Scan(&v, &g, &day)
+	if errors.Is(err, sql.ErrNoRows) {
+		// No legacy counters: nothing to migrate.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var n int64
+	if err := tx.QueryRow("SELECT COUNT(*) FROM metrics_daily").Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 || (v == 0 && g == 0) {
+		return nil
+	}
+	if _, err := tx.Exec("INSERT INTO metrics_daily (day, event, count) VALUES (?, ?, ?), (?, ?, ?)", day, EventVisit, v, day, EventPDF, g); err != nil {
+		return err
+	}
+	log.Printf("metrics: migrated lifetime counters (%d visits, %d generates) into metrics_daily", v, g)
+	return tx.Commit()
+}
+
+var sqliteDialect = dialect{
+	schema: []string{`
+        CREATE TABLE IF NOT EXISTS metrics_daily (
+            day TEXT NOT NULL,
+            event TEXT NOT NULL,
+            count INTEGER NOT NULL DEFAULT 0,
+            PRIMARY KEY (day, event)
+        )
+    `, `
+        CREATE TABLE IF NOT EXISTS metrics_visitors (
+            day TEXT PRIMARY KEY,
+            registers BLOB NOT NULL
+        )
+    `, `
+        CREATE TABLE IF NOT EXISTS metrics_visitor_salts (
+            day TEXT PRIMARY KEY,
+            salt BLOB NOT NULL
+        )
+    `},
+	upsertCounts: "INSERT INTO metrics_daily (day, event, count) VALUES %s ON CONFLICT(day, event) DO UPDATE SET count=count+excluded.count",
+	selectCounts: "SELECT day, event, count FROM metrics_daily WHERE day BETWEEN ? AND ?",
+	insertSalt:   "INSERT OR IGNORE INTO metrics_visitor_salts (day, salt) VALUES (?, ?)",
+	// The store uses a single connection, which serialises the merge.
+	lockSketch:     "SELECT registers FROM metrics_visitors WHERE day=?",
+	upsertSketch:   "INSERT INTO metrics_visitors (day, registers) VALUES (?, ?) ON CONFLICT(day) DO UPDATE SET registers=excluded.registers",
+	selectSketches: "SELECT day, registers FROM metrics_visitors WHERE day BETWEEN ? AND ?",
+}
+
+// SQLiteStore keeps the metrics in an SQLite file, for single-instance
+// deployments without MySQL.
+type SQLiteStore struct {
+	*sqlStore
+}
+
+// OpenSQLite opens or creates the SQLite database at path.
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	dsn := path
+	if !strings.Contains(dsn, "?") {
+		dsn += "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	s, err := newSQLStore(db, sqliteDialect)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.owned = true
+	return &SQLiteStore{s}, nil
+}
@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func hllOf(from, to uint64) *HLL {
+	h := &HLL{}
+	for i := from; i < to; i++ {
+		h.Add(mix(i))
+	}
+	return h
+}
+
+// mix spreads sequential ids over 64 bits, as the visitor hash does.
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// testStore runs the Store contract against s.
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+	if err := s.AddCounts(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, batch := range [][]Count{
+		{{"2026-10-01", EventVisit, 3}, {"2026-10-01", EventPDF, 1}},
+		{{"2026-10-01", EventVisit, 2}, {"2026-10-02", EventVisit, 7}, {"2026-11-01", EventImport, 1}},
+	} {
+		if err := s.AddCounts(ctx, batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	totals, err := s.Totals(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals[EventVisit] != 12 || totals[EventPDF] != 1 || totals[EventImport] != 1 {
+		t.Errorf("Totals: %v", totals)
+	}
+	counts, err := s.Counts(ctx, "2026-10-01", "2026-10-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Day+counts[i].Event < counts[j].Day+counts[j].Event })
+	want := []Count{{"2026-10-01", EventPDF, 1}, {"2026-10-01", EventVisit, 5}, {"2026-10-02", EventVisit, 7}}
+	if len(counts) != len(want) {
+		t.Fatalf("Counts: got %v, want %v", counts, want)
+	}
+	for i := range want {
+		if counts[i] != want[i] {
+			t.Errorf("Counts[%d]: got %v, want %v", i, counts[i], want[i])
+		}
+	}
+
+	a, b := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	if got, err := s.Salt(ctx, "2026-10-01", a); err != nil || !bytes.Equal(got, a) {
+		t.Fatalf("first Salt: %x %v", got, err)
+	}
+	if got, err := s.Salt(ctx, "2026-10-01", b); err != nil || !bytes.Equal(got, a) {
+		t.Errorf("Salt of the same day should keep the first: %x %v", got, err)
+	}
+	if got, err := s.Salt(ctx, "2026-10-02", b); err != nil || !bytes.Equal(got, b) {
+		t.Errorf("Salt of the next day: %x %v", got, err)
+	}
+	// The next day deleted the old salt, so asking again stores a new one.
+	if got, err := s.Salt(ctx, "2026-10-01", b); err != nil || !bytes.Equal(got, b) {
+		t.Errorf("old salt was not deleted: %x %v", got, err)
+	}
+
+	if err := s.MergeSketch(ctx, "2026-10-01", hllOf(0, 1000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MergeSketch(ctx, "2026-10-01", hllOf(500, 1500)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MergeSketch(ctx, "2026-10-03", hllOf(0, 10)); err != nil {
+		t.Fatal(err)
+	}
+	sketches, err := s.Sketches(ctx, "2026-10-01", "2026-10-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sketches) != 1 || sketches["2026-10-01"] == nil {
+		t.Fatalf("Sketches: %v", sketches)
+	}
+	if n := sketches["2026-10-01"].Estimate(); n < 1450 || n > 1550 {
+		t.Errorf("merged estimate %d, want about 1500", n)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.db")
+	s, err := Open("sqlite://" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything survives reopening the file.
+	s, err = Open("sqlite:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	totals, err := s.Totals(context.Background())
+	if err != nil || totals[EventVisit] != 12 {
+		t.Errorf("after reopen: %v %v", totals, err)
+	}
+	sketches, err := s.Sketches(context.Background(), "2026-10-01", "2026-10-01")
+	if err != nil || len(sketches) != 1 {
+		t.Errorf("sketches after reopen: %v %v", sketches, err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	for _, dsn := range []string{"memory:", "memory://"} {
+		s, err := Open(dsn)
+		if _, ok := s.(*MemoryStore); err != nil || !ok {
+			t.Errorf("Open(%q): %T %v", dsn, s, err)
+		}
+	}
+	for _, dsn := range []string{"", "sqlite:", "sqlite://", "postgres://x", "root:pw@tcp(db:3306)/resume"} {
+		if _, err := Open(dsn); err == nil {
+			t.Errorf("Open(%q): expected an error", dsn)
+		}
+	}
+}
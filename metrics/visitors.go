@@ -0,0 +1,227 @@
+package metrics
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// botMarkers are lower-case user-agent fragments of crawlers, link
+// previewers, HTTP libraries and uptime probes.
+var botMarkers = []string{
+	"bot", "crawl", "spider", "slurp", "facebookexternalhit", "embedly",
+	"curl", "wget", "python-requests", "python-urllib", "go-http-client", "java/", "okhttp", "libwww", "httpclient",
+	"headless", "lighthouse", "monitor", "uptime", "pingdom", "kube-probe", "healthcheck",
+}
+
+// IsBot reports whether a request with user agent ua is automated. An empty
+// user agent counts as a bot.
+func IsBot(ua string) bool {
+	ua = strings.ToLower(strings.TrimSpace(ua))
+	if ua == "" {
+		return true
+	}
+	for _, m := range botMarkers {
+		if strings.Contains(ua, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// revisitWindow is how long repeat page views of one visitor, such as
+// reloads, count as a single visit. The window is per instance.
+const revisitWindow = 30 * time.Minute
+
+// saltTimeout bounds the store call for a new day's salt.
+const saltTimeout = 2 * time.Second
+
+// Visitors are identified by a hash of IP address and user agent with a
+// random salt that changes every day. The salt is shared through the
+// store so that instances hash a visitor alike and their sketches merge,
+// and deleted once the day is over, after which the day's identifiers can
+// no longer be linked to anyone. Neither addresses nor identifiers are
+// stored; only the sketches are.
+var vis = struct {
+	mu       sync.Mutex
+	day      string
+	salt     []byte
+	seen     map[uint64]time.Time // last counted view per visitor, today
+	sketches map[string]*HLL      // by day
+	dirty    map[string]bool      // days with additions not yet in the store
+}{seen: map[uint64]time.Time{}, sketches: map[string]*HLL{}, dirty: map[string]bool{}}
+
+// CountVisit is Gin middleware counting page views: GET requests outside
+// static files, probes and the metrics endpoints. Bots are counted as
+// EventBot; people as EventVisit at most once per revisitWindow, and in the
+// day's unique-visitor sketch.
+func CountVisit(c *gin.Context) {
+	if c.Request.Method != "GET" {
+		return
+	}
+	p := c.Request.URL.Path
+	if strings.HasPrefix(p, "/static") || strings.HasPrefix(p, "/.well-known") || strings.HasPrefix(p, "/metrics") ||
+		p == "/robots.txt" || p == "/sitemap.xml" || p == "/favicon.ico" || p == "/healthz" {
+		return
+	}
+	ua := c.Request.UserAgent()
+	if IsBot(ua) {
+		Inc(EventBot)
+		return
+	}
+	if observeVisitor(time.Now(), c.ClientIP(), ua) {
+		IncVisit()
+	}
+}
+
+// observeVisitor adds the visitor to the day's sketch and reports whether
+// the view counts as a new visit.
+func observeVisitor(now time.Time, ip, ua string) bool {
+	day := now.Format(dayLayout)
+	vis.mu.Lock()
+	if vis.day != day {
+		// Fetch the salt without holding the lock, so that other visits do
+		// not wait for the store. Requests racing here get the same salt
+		// from the store; the first to return installs it.
+		vis.mu.Unlock()
+		salt := visitorSalt(day)
+		vis.mu.Lock()
+		if vis.day != day {
+			vis.day, vis.salt = day, salt
+			vis.seen = map[uint64]time.Time{}
+			pruneSketches(now)
+		}
+	}
+	defer vis.mu.Unlock()
+	sum := sha256.Sum256([]byte(string(vis.salt) + ip + "\x00" + ua))
+	id := binary.BigEndian.Uint64(sum[:8])
+	sketch(day).Add(id)
+	vis.dirty[day] = true
+
+	last, ok := vis.seen[id]
+	if ok && now.Sub(last) < revisitWindow {
+		return false
+	}
+	vis.seen[id] = now
+	if len(vis.seen) > 100000 {
+		for k, t := range vis.seen {
+			if now.Sub(t) >= revisitWindow {
+				delete(vis.seen, k)
+			}
+		}
+	}
+	return true
+}
+
+// sketch returns the local sketch of day; callers hold vis.mu.
+func sketch(day string) *HLL {
+	h, ok := vis.sketches[day]
+	if !ok {
+		h = &HLL{}
+		vis.sketches[day] = h
+	}
+	return h
+}
+
+// pruneSketches drops the local sketches from before yesterday that have
+// been written to the store. Callers hold vis.mu.
+func pruneSketches(now time.Time) {
+	cutoff := now.AddDate(0, 0, -1).Format(dayLayout)
+	for day := range vis.sketches {
+		if day < cutoff && !vis.dirty[day] {
+			delete(vis.sketches, day)
+		}
+	}
+}
+
+// visitorSalt returns the salt of day, agreeing on it with other instances
+// through the store. If the store fails, the instance uses a salt of its own.
+func visitorSalt(day string) []byte {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), saltTimeout)
+	defer cancel()
+	shared, err := store.Salt(ctx, day, salt)
+	if err != nil {
+		log.Printf("metrics salt err: %v", err)
+		return salt
+	}
+	return shared
+}
+
+// flushSketches merges the local sketches with changes into the store.
+// Merging is idempotent, so a sketch that fails is simply retried later.
+func flushSketches(ctx context.Context) error {
+	vis.mu.Lock()
+	batch := map[string]*HLL{}
+	for day := range vis.dirty {
+		h := *vis.sketches[day]
+		batch[day] = &h
+	}
+	vis.dirty = map[string]bool{}
+	vis.mu.Unlock()
+
+	var firstErr error
+	for day, h := range batch {
+		if err := store.MergeSketch(ctx, day, h); err != nil {
+			vis.mu.Lock()
+			vis.dirty[day] = true
+			vis.mu.Unlock()
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// uniqueVisitors returns the estimated unique visitors of each day from from
+// to to. Identifiers change daily, so days cannot be added up into unique
+// visitors over a range.
+func uniqueVisitors(from, to string, days []string) ([]Point, error) {
+	sketches, err := store.Sketches(context.Background(), from, to)
+	if err != nil {
+		return nil, err
+	}
+	vis.mu.Lock()
+	for day, local := range vis.sketches {
+		if day < from || day > to {
+			continue
+		}
+		if h, ok := sketches[day]; ok {
+			h.Merge(local)
+		} else {
+			h := *local
+			sketches[day] = &h
+		}
+	}
+	vis.mu.Unlock()
+	points := make([]Point, 0, len(days))
+	for _, day := range days {
+		var n int64
+		if h, ok := sketches[day]; ok {
+			n = h.Estimate()
+		}
+		points = append(points, Point{Day: day, Count: n})
+	}
+	return points, nil
+}
+
+// UniqueVisitorsToday estimates today's unique visitors across instances.
+func UniqueVisitorsToday() (int64, error) {
+	today := time.Now().Format(dayLayout)
+	points, err := uniqueVisitors(today, today, []string{today})
+	if err != nil {
+		return 0, err
+	}
+	return points[0].Count, nil
+}
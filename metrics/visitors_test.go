@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIsBot(t *testing.T) {
+	tests := map[string]bool{
+		"":   true,
+		"  ": true,
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)": true,
+		"curl/8.4.0":           true,
+		"python-requests/2.31": true,
+		"kube-probe/1.29":      true,
+		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0 Safari/537.36":    true,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36":  false,
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148 MicroMessenger/8.0": false,
+	}
+	for ua, want := range tests {
+		if got := IsBot(ua); got != want {
+			t.Errorf("IsBot(%q) = %v, want %v", ua, got, want)
+		}
+	}
+}
+
+func resetVisitors() {
+	vis.mu.Lock()
+	vis.day, vis.salt = "", nil
+	vis.seen = map[uint64]time.Time{}
+	vis.sketches = map[string]*HLL{}
+	vis.dirty = map[string]bool{}
+	vis.mu.Unlock()
+}
+
+func TestSaltRotation(t *testing.T) {
+	initTestStore(t, NewMemoryStore())
+	resetVisitors()
+	t.Cleanup(resetVisitors)
+	day1 := time.Date(2026, 10, 1, 23, 0, 0, 0, time.Local)
+
+	if !observeVisitor(day1, "10.0.0.1", "Mozilla") {
+		t.Fatal("first view should count")
+	}
+	if observeVisitor(day1.Add(10*time.Minute), "10.0.0.1", "Mozilla") {
+		t.Error("reload within the revisit window counted")
+	}
+	if !observeVisitor(day1.Add(10*time.Minute), "10.0.0.2", "Mozilla") {
+		t.Error("another visitor did not count")
+	}
+	salt1 := append([]byte(nil), vis.salt...)
+
+	day2 := day1.Add(2 * time.Hour)
+	if !observeVisitor(day2, "10.0.0.1", "Mozilla") {
+		t.Error("first view of the next day should count")
+	}
+	if bytes.Equal(vis.salt, salt1) {
+		t.Error("salt did not change with the day")
+	}
+	ms := store.(*MemoryStore)
+	if _, ok := ms.salts[day1.Format(dayLayout)]; ok {
+		t.Error("the previous day's salt was kept")
+	}
+	if err := flushSketches(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	points, err := uniqueVisitors("2026-10-01", "2026-10-02", []string{"2026-10-01", "2026-10-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points[0].Count != 2 || points[1].Count != 1 {
+		t.Errorf("unique visitors: %v", points)
+	}
+}
+
+// blockingStore holds Salt until release is closed.
+type blockingStore struct {
+	*MemoryStore
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Salt(ctx context.Context, day string, candidate []byte) ([]byte, error) {
+	close(s.entered)
+	<-s.release
+	return s.MemoryStore.Salt(ctx, day, candidate)
+}
+
+// A slow salt lookup does not hold the visitor lock.
+func TestSaltOutsideLock(t *testing.T) {
+	s := &blockingStore{MemoryStore: NewMemoryStore(), entered: make(chan struct{}), release: make(chan struct{})}
+	initTestStore(t, s)
+	resetVisitors()
+	t.Cleanup(resetVisitors)
+
+	done := make(chan bool)
+	go func() { done <- observeVisitor(time.Now(), "10.0.0.1", "Mozilla") }()
+	<-s.entered
+	read := make(chan error)
+	go func() {
+		_, err := UniqueVisitorsToday()
+		read <- err
+	}()
+	select {
+	case err := <-read:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("reading visitors waited for the salt lookup")
+	}
+	close(s.release)
+	if !<-done {
+		t.Error("view did not count")
+	}
+}
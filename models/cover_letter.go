@@ -0,0 +1,51 @@
+package models
+
+import "strings"
+
+// CoverLetter is the letter sent along with a resume. It carries the
+// resume's ThemeConfig so both print with the same template and colour.
+type CoverLetter struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+	Date  string `json:"date"`
+	// Recipient, Company and Position address the letter, e.g.
+	// "招聘负责人", "科技创新有限公司", "高级后端开发工程师".
+	Recipient string `json:"recipient"`
+	Company   string `json:"company"`
+	Position  string `json:"position"`
+	Greeting  string `json:"greeting"`
+	// Body holds the paragraphs, separated by blank lines.
+	Body    string      `json:"body"`
+	Closing string      `json:"closing"`
+	Config  ThemeConfig `json:"config"`
+}
+
+// NewCoverLetter starts a letter signed with r's contact details and
+// styled like r.
+func NewCoverLetter(r Resume) CoverLetter {
+	return CoverLetter{Name: r.Name, Email: r.Email, Phone: r.Phone, Config: r.Config}
+}
+
+// Paragraphs splits Body at blank lines. Single line breaks inside a
+// paragraph are kept.
+func (l CoverLetter) Paragraphs() []string {
+	var out []string
+	for _, p := range strings.Split(strings.ReplaceAll(l.Body, "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// ClosingLines splits Closing into its lines, e.g. "此致" and "敬礼！".
+func (l CoverLetter) ClosingLines() []string {
+	var out []string
+	for _, s := range strings.Split(l.Closing, "\n") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"strings"
+	"unicode/utf8"
+)
+
 type Exp struct {
 	Title       string `form:"title" json:"title"`
 	Company     string `form:"company" json:"company"`
@@ -13,23 +18,110 @@ type Edu struct {
 	Date   string `form:"date" json:"date"`
 }
 
+type Project struct {
+	Name        string `form:"name" json:"name"`
+	Role        string `form:"role" json:"role"`
+	Date        string `form:"date" json:"date"`
+	URL         string `form:"url" json:"url"`
+	Description string `form:"description" json:"description"`
+}
+
+type Certification struct {
+	Name   string `form:"name" json:"name"`
+	Issuer string `form:"issuer" json:"issuer"`
+	Date   string `form:"date" json:"date"`
+}
+
+type Award struct {
+	Title       string `form:"title" json:"title"`
+	Issuer      string `form:"issuer" json:"issuer"`
+	Date        string `form:"date" json:"date"`
+	Description string `form:"description" json:"description"`
+}
+
+type SkillItem struct {
+	Name  string `form:"name" json:"name"`
+	Level string `form:"level" json:"level"`
+}
+
+type SkillGroup struct {
+	Category string      `form:"category" json:"category"`
+	Items    []SkillItem `form:"items" json:"items"`
+}
+
+// ItemsText formats the items the way the editor's single-line input takes
+// them, e.g. "Go:精通, MySQL". ParseSkillItems is the inverse.
+func (g SkillGroup) ItemsText() string {
+	parts := make([]string, 0, len(g.Items))
+	for _, it := range g.Items {
+		if it.Level != "" {
+			parts = append(parts, it.Name+":"+it.Level)
+		} else {
+			parts = append(parts, it.Name)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// DisplayText formats the items for print output, e.g. "Go（精通）、MySQL".
+func (g SkillGroup) DisplayText() string {
+	parts := make([]string, 0, len(g.Items))
+	for _, it := range g.Items {
+		if it.Level != "" {
+			parts = append(parts, it.Name+"（"+it.Level+"）")
+		} else {
+			parts = append(parts, it.Name)
+		}
+	}
+	return strings.Join(parts, "、")
+}
+
+// ParseSkillItems splits a comma-separated list of "name" or "name:level"
+// entries. Both ASCII and full-width separators are accepted.
+func ParseSkillItems(s string) []SkillItem {
+	var items []SkillItem
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '，' || r == '、' || r == ';' || r == '；' || r == '\n'
+	}) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, level := part, ""
+		if i := strings.IndexAny(part, ":："); i >= 0 {
+			name = strings.TrimSpace(part[:i])
+			_, size := utf8.DecodeRuneInString(part[i:])
+			level = strings.TrimSpace(part[i+size:])
+		}
+		items = append(items, SkillItem{Name: name, Level: level})
+	}
+	return items
+}
+
 type ThemeConfig struct {
 	Template  string `form:"template" json:"template"`
 	Color     string `form:"color" json:"color"`
 	Font      string `form:"font" json:"font"`
 	FontSize  string `form:"font_size" json:"font_size"`
 	PaperSize string `form:"paper_size" json:"paper_size"`
+	// SectionOrder lists section keys (see Resume.Sections) in render order.
+	SectionOrder []string `form:"section_order" json:"section_order"`
 }
 
 type Resume struct {
-	Name       string      `form:"name" json:"name"`
-	Email      string      `form:"email" json:"email"`
-	Phone      string      `form:"phone" json:"phone"`
-	Avatar     string      `form:"-" json:"avatar"` // File path
-	Summary    string      `form:"summary" json:"summary"`
-	Experience []Exp       `form:"experience" json:"experience"`
-	Education  []Edu       `form:"education" json:"education"`
-	Config     ThemeConfig `form:"config" json:"config"`
+	Name           string          `form:"name" json:"name"`
+	Email          string          `form:"email" json:"email"`
+	Phone          string          `form:"phone" json:"phone"`
+	Avatar         string          `form:"-" json:"avatar"` // File path
+	Summary        string          `form:"summary" json:"summary"`
+	Experience     []Exp           `form:"experience" json:"experience"`
+	Education      []Edu           `form:"education" json:"education"`
+	Skills         []SkillGroup    `form:"skills" json:"skills"`
+	Projects       []Project       `form:"projects" json:"projects"`
+	Certifications []Certification `form:"certifications" json:"certifications"`
+	Awards         []Award         `form:"awards" json:"awards"`
+	Custom         []CustomSection `form:"custom_sections" json:"custom_sections"`
+	Config         ThemeConfig     `form:"config" json:"config"`
 }
 
 func GetDemoResume() Resume {
@@ -59,5 +151,38 @@ func GetDemoResume() Resume {
 				Date:   "2014 - 2018",
 			},
 		},
+		Skills: []SkillGroup{
+			{
+				Category: "编程语言",
+				Items:    []SkillItem{{Name: "Go", Level: "精通"}, {Name: "Python", Level: "熟练"}, {Name: "TypeScript", Level: "熟练"}},
+			},
+			{
+				Category: "框架与工具",
+				Items:    []SkillItem{{Name: "Gin"}, {Name: "React"}, {Name: "MySQL"}, {Name: "Docker"}, {Name: "Kubernetes"}},
+			},
+		},
+		Projects: []Project{
+			{
+				Name:        "简单简历",
+				Role:        "作者",
+				Date:        "2023 - 至今",
+				URL:         "https://github.com/dongzhiwei-git/resume",
+				Description: "基于 Go + Gin 的在线简历生成器，支持实时预览、多模板与 PDF 导出。",
+			},
+		},
+		Certifications: []Certification{
+			{
+				Name:   "AWS Certified Solutions Architect – Associate",
+				Issuer: "Amazon Web Services",
+				Date:   "2021.06",
+			},
+		},
+		Awards: []Award{
+			{
+				Title:  "年度优秀员工",
+				Issuer: "某某科技有限公司",
+				Date:   "2021",
+			},
+		},
 	}
 }
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Keys of the built-in sections, as used in ThemeConfig.SectionOrder.
+// Custom sections are addressed by position: "custom.0", "custom.1", ...
+const (
+	SectionSummary        = "summary"
+	SectionSkills         = "skills"
+	SectionExperience     = "experience"
+	SectionProjects       = "projects"
+	SectionEducation      = "education"
+	SectionCertifications = "certifications"
+	SectionAwards         = "awards"
+	SectionCustom         = "custom"
+)
+
+// DefaultSectionOrder is used for any built-in section SectionOrder leaves out.
+var DefaultSectionOrder = []string{
+	SectionSummary,
+	SectionSkills,
+	SectionExperience,
+	SectionProjects,
+	SectionEducation,
+	SectionCertifications,
+	SectionAwards,
+}
+
+type CustomItem struct {
+	Heading  string `form:"heading" json:"heading"`
+	Subtitle string `form:"subtitle" json:"subtitle"`
+	Date     string `form:"date" json:"date"`
+	Body     string `form:"body" json:"body"`
+}
+
+// CustomSection is a user-defined section such as publications, patents or
+// volunteering.
+type CustomSection struct {
+	Title string       `form:"title" json:"title"`
+	Items []CustomItem `form:"items" json:"items"`
+}
+
+// ItemsText formats the items the way the editor's textarea takes them: one
+// block per item separated by a blank line, whose first line is
+// "heading | subtitle | date" and whose remaining lines are the body.
+// ParseCustomItems is the inverse.
+func (s CustomSection) ItemsText() string {
+	blocks := make([]string, 0, len(s.Items))
+	for _, it := range s.Items {
+		head := strings.TrimRight(strings.Join([]string{it.Heading, it.Subtitle, it.Date}, " | "), " |")
+		if it.Body != "" {
+			head += "\n" + it.Body
+		}
+		blocks = append(blocks, head)
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+func ParseCustomItems(s string) []CustomItem {
+	var items []CustomItem
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	for _, block := range strings.Split(s, "\n\n") {
+		block = strings.Trim(block, "\n")
+		if strings.TrimSpace(block) == "" {
+			continue
+		}
+		head, body, _ := strings.Cut(block, "\n")
+		parts := strings.SplitN(head, "|", 3)
+		var it CustomItem
+		it.Heading = strings.TrimSpace(parts[0])
+		if len(parts) > 1 {
+			it.Subtitle = strings.TrimSpace(parts[1])
+		}
+		if len(parts) > 2 {
+			it.Date = strings.TrimSpace(parts[2])
+		}
+		it.Body = strings.TrimSpace(body)
+		items = append(items, it)
+	}
+	return items
+}
+
+// ParseSectionOrder splits a comma-separated list of section keys.
+func ParseSectionOrder(s string) []string {
+	var keys []string
+	for _, k := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '，' || r == ' ' || r == '\n'
+	}) {
+		keys = append(keys, strings.ToLower(k))
+	}
+	return keys
+}
+
+// Section is one entry of the resolved render order. Custom is set only for
+// user-defined sections.
+type Section struct {
+	Key    string
+	Custom *CustomSection
+}
+
+// Sections resolves Config.SectionOrder into the order sections are rendered
+// in. Unknown or duplicate keys are ignored; built-in sections that are not
+// listed follow in DefaultSectionOrder, then unlisted custom sections in
+// their own order.
+func (r Resume) Sections() []Section {
+	var out []Section
+	seen := map[string]bool{}
+	add := func(key string) {
+		if strings.HasPrefix(key, SectionCustom+".") {
+			i, err := strconv.Atoi(strings.TrimPrefix(key, SectionCustom+"."))
+			if err != nil || i < 0 || i >= len(r.Custom) {
+				return
+			}
+			// "custom.01" and "custom.1" name the same section.
+			key = SectionCustom + "." + strconv.Itoa(i)
+			if seen[key] {
+				return
+			}
+			seen[key] = true
+			out = append(out, Section{Key: SectionCustom, Custom: &r.Custom[i]})
+			return
+		}
+		if seen[key] {
+			return
+		}
+		for _, k := range DefaultSectionOrder {
+			if k == key {
+				seen[key] = true
+				out = append(out, Section{Key: key})
+				return
+			}
+		}
+	}
+	for _, k := range r.Config.SectionOrder {
+		add(strings.TrimSpace(k))
+	}
+	for _, k := range DefaultSectionOrder {
+		add(k)
+	}
+	for i := range r.Custom {
+		add(SectionCustom + "." + strconv.Itoa(i))
+	}
+	return out
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSections(t *testing.T) {
+	custom := []CustomSection{{Title: "Publications"}, {Title: "Patents"}}
+	tests := []struct {
+		name  string
+		order []string
+		want  []string
+	}{
+		{"default", nil, []string{
+			"summary", "skills", "experience", "projects", "education", "certifications", "awards",
+			"Publications", "Patents",
+		}},
+		{"custom first", []string{"custom.1", "experience"}, []string{
+			"Patents", "experience",
+			"summary", "skills", "projects", "education", "certifications", "awards",
+			"Publications",
+		}},
+		{"duplicates", []string{"skills", "skills", "custom.01", "custom.1", "custom.+1"}, []string{
+			"skills", "Patents",
+			"summary", "experience", "projects", "education", "certifications", "awards",
+			"Publications",
+		}},
+		{"unknown", []string{"hobbies", "custom.2", "custom.-1", "custom.x"}, []string{
+			"summary", "skills", "experience", "projects", "education", "certifications", "awards",
+			"Publications", "Patents",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Resume{Custom: custom, Config: ThemeConfig{SectionOrder: tt.order}}
+			var got []string
+			for _, s := range r.Sections() {
+				if s.Custom != nil {
+					got = append(got, s.Custom.Title)
+				} else {
+					got = append(got, s.Key)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sections() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,512 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dongzhiwei-git/resume/models"
+	"github.com/go-pdf/fpdf"
+)
+
+// fontPaths lists well-known locations of a TrueType font with CJK coverage,
+// tried in order when PDF_FONT_PATH is not set.
+var fontPaths = []string{
+	"/usr/share/fonts/droid-nonlatin/DroidSansFallbackFull.ttf", // alpine: font-droid-nonlatin
+	"/usr/share/fonts/truetype/droid/DroidSansFallbackFull.ttf", // debian: fonts-droid-fallback
+	"/usr/share/fonts/google-droid-sans-fonts/DroidSansFallbackFull.ttf",
+	"/Library/Fonts/Arial Unicode.ttf",
+	"/System/Library/Fonts/Supplemental/Arial Unicode.ttf",
+	"C:\\Windows\\Fonts\\simhei.ttf",
+}
+
+var fontMu sync.Mutex
+var fontCache = map[string][]byte{}
+
+// Native lays the resume out directly with fpdf, embedding a subset of a
+// CJK-capable TrueType font so the output does not depend on viewer fonts.
+type Native struct {
+	FontPath string
+}
+
+func loadFont(path string) []byte {
+	candidates := fontPaths
+	if path != "" {
+		candidates = []string{path}
+	}
+	fontMu.Lock()
+	defer fontMu.Unlock()
+	for _, p := range candidates {
+		if b, ok := fontCache[p]; ok {
+			return b
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		fontCache[p] = b
+		return b
+	}
+	return nil
+}
+
+type doc struct {
+	f      *fpdf.Fpdf
+	family string
+	tr     func(string) string
+	color  [3]int
+	base   float64
+	tpl    string
+}
+
+// newDoc starts a one-page document styled by cfg.
+func (n *Native) newDoc(cfg models.ThemeConfig, title string) *doc {
+	size := "A4"
+	if strings.EqualFold(cfg.PaperSize, "letter") {
+		size = "Letter"
+	}
+	f := fpdf.New("P", "mm", size, "")
+	f.SetMargins(15, 15, 15)
+	f.SetAutoPageBreak(true, 15)
+	f.SetCellMargin(0)
+
+	d := &doc{f: f, color: parseColor(cfg.Color), base: baseSize(cfg.FontSize), tpl: cfg.Template}
+	if font := loadFont(n.FontPath); font != nil {
+		f.AddUTF8FontFromBytes("cjk", "", font)
+		d.family = "cjk"
+		d.tr = bmpOnly
+	} else {
+		log.Printf("pdf: no CJK font found, set PDF_FONT_PATH; falling back to Helvetica")
+		d.family = "Helvetica"
+		d.tr = f.UnicodeTranslatorFromDescriptor("")
+	}
+	f.SetTitle(title, true)
+	f.SetCreator("简单简历", true)
+	f.AddPage()
+	return d
+}
+
+func (n *Native) Render(w io.Writer, r models.Resume) error {
+	d := n.newDoc(r.Config, r.Name)
+	d.header(r)
+	for _, sec := range r.Sections() {
+		d.section(r, sec)
+	}
+	return d.f.Output(w)
+}
+
+// RenderCoverLetter lays out a letter under the same header as the resume.
+func (n *Native) RenderCoverLetter(w io.Writer, l models.CoverLetter) error {
+	d := n.newDoc(l.Config, l.Name)
+	f := d.f
+	h := d.lineHeight(d.base)
+	d.header(models.Resume{Name: l.Name, Email: l.Email, Phone: l.Phone})
+
+	if l.Date != "" {
+		f.SetTextColor(102, 102, 102)
+		d.font("", d.base)
+		f.CellFormat(0, h, d.tr(l.Date), "", 1, "R", false, 0, "")
+	}
+	addressee := strings.TrimSpace(l.Company + " " + l.Recipient)
+	if addressee != "" {
+		f.SetTextColor(34, 34, 34)
+		d.font("B", d.base)
+		f.CellFormat(0, h, d.tr(addressee), "", 1, "L", false, 0, "")
+	}
+	if l.Position != "" {
+		d.subtitle("应聘职位：" + l.Position)
+	}
+	f.Ln(4)
+	if l.Greeting != "" {
+		d.paragraph(l.Greeting)
+		f.Ln(1)
+	}
+	f.SetTextColor(51, 51, 51)
+	d.font("", d.base)
+	for _, p := range l.Paragraphs() {
+		// Two ideographic spaces stand in for the first-line indent.
+		f.MultiCell(0, h, d.tr("\u3000\u3000"+p), "", "L", false)
+		f.Ln(3)
+	}
+	f.Ln(4)
+	for _, s := range l.ClosingLines() {
+		d.paragraph(s)
+	}
+	f.Ln(2)
+	f.SetTextColor(d.color[0], d.color[1], d.color[2])
+	d.font("B", d.base)
+	f.CellFormat(0, h, d.tr(l.Name), "", 1, "R", false, 0, "")
+	return f.Output(w)
+}
+
+// section writes one resume section; empty sections are skipped.
+func (d *doc) section(r models.Resume, sec models.Section) {
+	switch sec.Key {
+	case models.SectionCustom:
+		cs := sec.Custom
+		if len(cs.Items) == 0 {
+			return
+		}
+		d.sectionTitle(cs.Title)
+		for _, it := range cs.Items {
+			if it.Heading != "" || it.Date != "" {
+				d.itemHeader(it.Heading, it.Date)
+			}
+			d.subtitle(it.Subtitle)
+			d.paragraph(it.Body)
+		}
+		d.f.Ln(d.lineHeight(d.base) * 0.5)
+	case models.SectionSummary:
+		if strings.TrimSpace(r.Summary) != "" {
+			d.sectionTitle("个人简介")
+			d.paragraph(r.Summary)
+		}
+	case models.SectionSkills:
+		var skills []models.SkillGroup
+		for _, g := range r.Skills {
+			if len(g.Items) > 0 {
+				skills = append(skills, g)
+			}
+		}
+		if len(skills) > 0 {
+			d.sectionTitle("专业技能")
+			for _, g := range skills {
+				d.skillLine(g)
+			}
+			d.f.Ln(d.lineHeight(d.base) * 0.5)
+		}
+	case models.SectionExperience:
+		var exps []models.Exp
+		for _, e := range r.Experience {
+			if e.Company != "" {
+				exps = append(exps, e)
+			}
+		}
+		if len(exps) > 0 {
+			d.sectionTitle("工作经历")
+			for _, e := range exps {
+				d.itemHeader(e.Title, e.Date)
+				d.subtitle(e.Company)
+				d.paragraph(e.Description)
+				d.f.Ln(d.lineHeight(d.base) * 0.5)
+			}
+		}
+	case models.SectionProjects:
+		var projs []models.Project
+		for _, p := range r.Projects {
+			if p.Name != "" {
+				projs = append(projs, p)
+			}
+		}
+		if len(projs) > 0 {
+			d.sectionTitle("项目经历")
+			for _, p := range projs {
+				d.itemHeader(p.Name, p.Date)
+				d.subtitle(strings.TrimSpace(p.Role + "  " + p.URL))
+				d.paragraph(p.Description)
+				d.f.Ln(d.lineHeight(d.base) * 0.5)
+			}
+		}
+	case models.SectionEducation:
+		var edus []models.Edu
+		for _, e := range r.Education {
+			if e.School != "" {
+				edus = append(edus, e)
+			}
+		}
+		if len(edus) > 0 {
+			d.sectionTitle("教育背景")
+			for _, e := range edus {
+				d.itemHeader(e.School, e.Date)
+				d.subtitle(e.Degree)
+				d.f.Ln(d.lineHeight(d.base) * 0.5)
+			}
+		}
+	case models.SectionCertifications:
+		var certs []models.Certification
+		for _, c := range r.Certifications {
+			if c.Name != "" {
+				certs = append(certs, c)
+			}
+		}
+		if len(certs) > 0 {
+			d.sectionTitle("资格证书")
+			for _, c := range certs {
+				d.itemHeader(c.Name, c.Date)
+				d.subtitle(c.Issuer)
+			}
+			d.f.Ln(d.lineHeight(d.base) * 0.5)
+		}
+	case models.SectionAwards:
+		var awards []models.Award
+		for _, a := range r.Awards {
+			if a.Title != "" {
+				awards = append(awards, a)
+			}
+		}
+		if len(awards) > 0 {
+			d.sectionTitle("荣誉奖项")
+			for _, a := range awards {
+				d.itemHeader(a.Title, a.Date)
+				d.subtitle(a.Issuer)
+				d.paragraph(a.Description)
+			}
+		}
+	}
+}
+
+func (d *doc) font(style string, size float64) {
+	if d.family != "Helvetica" {
+		style = ""
+	}
+	d.f.SetFont(d.family, style, size)
+}
+
+func (d *doc) lineHeight(size float64) float64 { return size * 0.3528 * 1.5 }
+
+func (d *doc) contentWidth() float64 {
+	pw, _ := d.f.GetPageSize()
+	l, _, r, _ := d.f.GetMargins()
+	return pw - l - r
+}
+
+func (d *doc) header(r models.Resume) {
+	f := d.f
+	pw, _ := f.GetPageSize()
+	left, top, _, _ := f.GetMargins()
+	nameSize := d.base * 2.4
+	contact := r.Email
+	if r.Phone != "" {
+		if contact != "" {
+			contact += "  |  "
+		}
+		contact += r.Phone
+	}
+	avatar := avatarImage(f, r.Avatar)
+	const avatarSize = 25.0
+
+	switch d.tpl {
+	case "modern":
+		bandH := top + d.lineHeight(nameSize) + d.lineHeight(d.base) + 10
+		if avatar != "" && bandH < avatarSize+16 {
+			bandH = avatarSize + 16
+		}
+		f.SetFillColor(d.color[0], d.color[1], d.color[2])
+		f.Rect(0, 0, pw, bandH, "F")
+		if avatar != "" {
+			drawAvatar(f, avatar, pw-left-avatarSize, (bandH-avatarSize)/2, avatarSize)
+		}
+		f.SetXY(left, top)
+		f.SetTextColor(255, 255, 255)
+		d.font("B", nameSize)
+		f.CellFormat(0, d.lineHeight(nameSize), d.tr(r.Name), "", 1, "L", false, 0, "")
+		d.font("", d.base)
+		f.CellFormat(0, d.lineHeight(d.base), d.tr(contact), "", 1, "L", false, 0, "")
+		f.SetY(bandH + 8)
+	case "minimal":
+		y := f.GetY()
+		if avatar != "" {
+			drawAvatar(f, avatar, pw-left-avatarSize, y, avatarSize)
+		}
+		f.SetTextColor(d.color[0], d.color[1], d.color[2])
+		d.font("", nameSize)
+		f.CellFormat(0, d.lineHeight(nameSize), d.tr(r.Name), "", 1, "L", false, 0, "")
+		f.SetTextColor(85, 85, 85)
+		d.font("", d.base)
+		f.CellFormat(0, d.lineHeight(d.base), d.tr(contact), "", 1, "L", false, 0, "")
+		if avatar != "" && f.GetY() < y+avatarSize {
+			f.SetY(y + avatarSize)
+		}
+		f.Ln(6)
+	default:
+		if avatar != "" {
+			drawAvatar(f, avatar, (pw-avatarSize)/2, f.GetY(), avatarSize)
+			f.SetY(f.GetY() + avatarSize + 4)
+		}
+		f.SetTextColor(d.color[0], d.color[1], d.color[2])
+		d.font("B", nameSize)
+		f.CellFormat(0, d.lineHeight(nameSize), d.tr(r.Name), "", 1, "C", false, 0, "")
+		f.SetTextColor(85, 85, 85)
+		d.font("", d.base)
+		f.CellFormat(0, d.lineHeight(d.base), d.tr(contact), "", 1, "C", false, 0, "")
+		y := f.GetY() + 3
+		f.SetDrawColor(d.color[0], d.color[1], d.color[2])
+		f.SetLineWidth(0.6)
+		f.Line(left, y, left+d.contentWidth(), y)
+		f.SetY(y + 6)
+	}
+}
+
+func (d *doc) sectionTitle(title string) {
+	f := d.f
+	left, _, _, _ := f.GetMargins()
+	size := d.base * 1.3
+	h := d.lineHeight(size)
+	_, ph := f.GetPageSize()
+	_, _, _, bottom := f.GetMargins()
+	// Keep the title together with at least one line of its content.
+	if f.GetY()+h+d.lineHeight(d.base)*2 > ph-bottom {
+		f.AddPage()
+	}
+	f.SetTextColor(d.color[0], d.color[1], d.color[2])
+	d.font("B", size)
+	switch d.tpl {
+	case "minimal":
+		f.SetFillColor(244, 244, 244)
+		f.SetCellMargin(2)
+		f.CellFormat(0, h+1, d.tr(title), "", 1, "L", true, 0, "")
+		f.SetCellMargin(0)
+	case "modern":
+		f.CellFormat(0, h, d.tr(title), "", 1, "L", false, 0, "")
+		y := f.GetY() + 0.5
+		f.SetDrawColor(d.color[0], d.color[1], d.color[2])
+		f.SetLineWidth(0.6)
+		f.Line(left, y, left+f.GetStringWidth(d.tr(title))+4, y)
+	default:
+		f.CellFormat(0, h, d.tr(title), "", 1, "L", false, 0, "")
+		y := f.GetY() + 0.5
+		f.SetDrawColor(238, 238, 238)
+		f.SetLineWidth(0.3)
+		f.Line(left, y, left+d.contentWidth(), y)
+	}
+	f.Ln(3)
+}
+
+func (d *doc) itemHeader(title, date string) {
+	f := d.f
+	titleSize, dateSize := d.base*1.1, d.base*0.9
+	h := d.lineHeight(titleSize)
+	dateW := 0.0
+	if date != "" {
+		d.font("I", dateSize)
+		dateW = f.GetStringWidth(d.tr(date)) + 2
+	}
+	f.SetTextColor(34, 34, 34)
+	d.font("B", titleSize)
+	f.CellFormat(d.contentWidth()-dateW, h, d.tr(title), "", 0, "L", false, 0, "")
+	f.SetTextColor(102, 102, 102)
+	d.font("I", dateSize)
+	f.CellFormat(dateW, h, d.tr(date), "", 1, "R", false, 0, "")
+}
+
+func (d *doc) subtitle(s string) {
+	if s == "" {
+		return
+	}
+	d.f.SetTextColor(85, 85, 85)
+	d.font("B", d.base)
+	d.f.CellFormat(0, d.lineHeight(d.base), d.tr(s), "", 1, "L", false, 0, "")
+}
+
+// skillLine writes "category  item, item" with the category in bold; the
+// minimal template leaves out proficiency levels.
+func (d *doc) skillLine(g models.SkillGroup) {
+	h := d.lineHeight(d.base)
+	if g.Category != "" {
+		d.f.SetTextColor(34, 34, 34)
+		d.font("B", d.base)
+		d.f.CellFormat(d.f.GetStringWidth(d.tr(g.Category))+4, h, d.tr(g.Category), "", 0, "L", false, 0, "")
+	}
+	if d.tpl == "minimal" {
+		items := make([]models.SkillItem, len(g.Items))
+		for i, it := range g.Items {
+			items[i] = models.SkillItem{Name: it.Name}
+		}
+		g.Items = items
+	}
+	d.f.SetTextColor(51, 51, 51)
+	d.font("", d.base)
+	d.f.MultiCell(0, h, d.tr(g.DisplayText()), "", "L", false)
+}
+
+func (d *doc) paragraph(s string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return
+	}
+	d.f.SetTextColor(51, 51, 51)
+	d.font("", d.base)
+	d.f.MultiCell(0, d.lineHeight(d.base), d.tr(s), "", "L", false)
+	d.f.Ln(1)
+}
+
+// avatarImage registers an uploaded avatar with the document and returns its
+// image name, or "" if there is none. Only files under static/uploads are read.
+// The image is re-encoded as baseline JPEG because fpdf rejects some variants
+// (progressive JPEG, interlaced PNG) that browsers upload happily.
+func avatarImage(f *fpdf.Fpdf, src string) string {
+	if src == "" {
+		return ""
+	}
+	p := filepath.Clean(strings.TrimPrefix(src, "/"))
+	if !strings.HasPrefix(p, filepath.Join("static", "uploads")+string(filepath.Separator)) {
+		return ""
+	}
+	fh, err := os.Open(p)
+	if err != nil {
+		return ""
+	}
+	defer fh.Close()
+	img, _, err := image.Decode(fh)
+	if err != nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return ""
+	}
+	f.RegisterImageOptionsReader("avatar", fpdf.ImageOptions{ImageType: "jpg"}, &buf)
+	if f.Err() {
+		return ""
+	}
+	return "avatar"
+}
+
+func drawAvatar(f *fpdf.Fpdf, name string, x, y, size float64) {
+	f.ClipCircle(x+size/2, y+size/2, size/2, false)
+	f.ImageOptions(name, x, y, size, size, false, fpdf.ImageOptions{ImageType: "jpg"}, 0, "")
+	f.ClipEnd()
+}
+
+func baseSize(s string) float64 {
+	switch s {
+	case "small":
+		return 9.5
+	case "large":
+		return 11.5
+	}
+	return 10.5
+}
+
+func parseColor(s string) [3]int {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) == 6 {
+		if v, err := strconv.ParseUint(s, 16, 32); err == nil {
+			return [3]int{int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff)}
+		}
+	}
+	return [3]int{0x33, 0x33, 0x33}
+}
+
+// bmpOnly drops characters fpdf's UTF-8 fonts cannot address (emoji and other
+// supplementary-plane runes) along with stray control characters.
+func bmpOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r > 0xFFFF || (r < 0x20 && r != '\n') {
+			return -1
+		}
+		return r
+	}, s)
+}
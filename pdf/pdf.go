@@ -0,0 +1,40 @@
+package pdf
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dongzhiwei-git/resume/models"
+)
+
+var (
+	ErrNotConfigured = errors.New("pdf service not configured")
+	ErrUnavailable   = errors.New("pdf service unavailable")
+)
+
+// Renderer turns a resume, or the cover letter that goes with it, into a
+// PDF document written to w.
+type Renderer interface {
+	Render(w io.Writer, r models.Resume) error
+	RenderCoverLetter(w io.Writer, l models.CoverLetter) error
+}
+
+// FromEnv picks the backend named by PDF_RENDERER ("native" or "remote").
+// When unset, the remote HTML-to-PDF service is used if PDF_API_URL and
+// PDF_API_KEY are both present, otherwise the in-process renderer.
+func FromEnv() Renderer {
+	remote := &Remote{URL: os.Getenv("PDF_API_URL"), Key: os.Getenv("PDF_API_KEY")}
+	native := &Native{FontPath: os.Getenv("PDF_FONT_PATH")}
+	switch strings.ToLower(os.Getenv("PDF_RENDERER")) {
+	case "remote":
+		return remote
+	case "native":
+		return native
+	}
+	if remote.URL != "" && remote.Key != "" {
+		return remote
+	}
+	return native
+}
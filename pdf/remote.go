@@ -0,0 +1,69 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/dongzhiwei-git/resume/models"
+)
+
+// Remote posts the rendered resume HTML to an external HTML-to-PDF API.
+type Remote struct {
+	URL string
+	Key string
+}
+
+func (s *Remote) Render(w io.Writer, r models.Resume) error {
+	return s.render(w, "resume_content.html", map[string]any{"Resume": r}, r.Config.PaperSize)
+}
+
+func (s *Remote) RenderCoverLetter(w io.Writer, l models.CoverLetter) error {
+	return s.render(w, "cover_letter_content.html", map[string]any{"Letter": l}, l.Config.PaperSize)
+}
+
+// render executes the named page template and posts the HTML. Both page
+// templates are parsed together because they share the theme styles.
+func (s *Remote) render(w io.Writer, name string, data map[string]any, paperSize string) error {
+	if s.URL == "" || s.Key == "" {
+		return ErrNotConfigured
+	}
+	tpl, err := template.ParseFiles("templates/resume_content.html", "templates/cover_letter_content.html")
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := tpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+	cssBytes, _ := os.ReadFile("static/css/style.css")
+	html := "<!DOCTYPE html><html><head><meta charset=\"utf-8\"><style>" + string(cssBytes) + "</style></head><body>" + buf.String() + "</body></html>"
+
+	payload := map[string]any{
+		"html": html,
+		"options": map[string]any{
+			"printBackground": true,
+			"format":          strings.ToUpper(paperSize),
+			"margin":          map[string]string{"top": "0.5in", "bottom": "0.5in", "left": "0.5in", "right": "0.5in"},
+		},
+	}
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", s.URL, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+s.Key)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("pdf service status %d", resp.StatusCode)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
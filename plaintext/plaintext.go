@@ -0,0 +1,183 @@
+package plaintext
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dongzhiwei-git/resume/models"
+)
+
+// Write renders r as plain UTF-8 text suitable for pasting into web forms.
+func Write(w io.Writer, r models.Resume) error {
+	b := &bytes.Buffer{}
+	b.WriteString(r.Name + "\n")
+	b.WriteString(join(" | ", r.Email, r.Phone) + "\n")
+
+	for _, sec := range r.Sections() {
+		writeSection(b, r, sec)
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// String returns the same text as Write, for callers that match against it.
+func String(r models.Resume) string {
+	var b strings.Builder
+	Write(&b, r)
+	return b.String()
+}
+
+// writeSection writes one resume section; empty sections are skipped.
+func writeSection(b *bytes.Buffer, r models.Resume, sec models.Section) {
+	switch sec.Key {
+	case models.SectionCustom:
+		cs := sec.Custom
+		if len(cs.Items) == 0 {
+			return
+		}
+		section(b, cs.Title)
+		for i, it := range cs.Items {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			if head := join(" | ", it.Heading, it.Subtitle, it.Date); head != "" {
+				b.WriteString(head + "\n")
+			}
+			indent(b, it.Body)
+		}
+	case models.SectionSummary:
+		if s := strings.TrimSpace(r.Summary); s != "" {
+			section(b, "个人简介")
+			b.WriteString(s + "\n")
+		}
+	case models.SectionSkills:
+		var skills []models.SkillGroup
+		for _, g := range r.Skills {
+			if len(g.Items) > 0 {
+				skills = append(skills, g)
+			}
+		}
+		if len(skills) > 0 {
+			section(b, "专业技能")
+			for _, g := range skills {
+				if g.Category != "" {
+					b.WriteString(g.Category + "：")
+				}
+				b.WriteString(g.DisplayText() + "\n")
+			}
+		}
+	case models.SectionExperience:
+		var exps []models.Exp
+		for _, e := range r.Experience {
+			if e.Company != "" {
+				exps = append(exps, e)
+			}
+		}
+		if len(exps) > 0 {
+			section(b, "工作经历")
+			for i, e := range exps {
+				if i > 0 {
+					b.WriteString("\n")
+				}
+				b.WriteString(join(" | ", e.Title, e.Company, e.Date) + "\n")
+				indent(b, e.Description)
+			}
+		}
+	case models.SectionProjects:
+		var projs []models.Project
+		for _, p := range r.Projects {
+			if p.Name != "" {
+				projs = append(projs, p)
+			}
+		}
+		if len(projs) > 0 {
+			section(b, "项目经历")
+			for i, p := range projs {
+				if i > 0 {
+					b.WriteString("\n")
+				}
+				b.WriteString(join(" | ", p.Name, p.Role, p.Date) + "\n")
+				if p.URL != "" {
+					b.WriteString("  " + p.URL + "\n")
+				}
+				indent(b, p.Description)
+			}
+		}
+	case models.SectionEducation:
+		var edus []models.Edu
+		for _, e := range r.Education {
+			if e.School != "" {
+				edus = append(edus, e)
+			}
+		}
+		if len(edus) > 0 {
+			section(b, "教育背景")
+			for _, e := range edus {
+				b.WriteString(join(" | ", e.School, e.Degree, e.Date) + "\n")
+			}
+		}
+	case models.SectionCertifications:
+		var certs []models.Certification
+		for _, c := range r.Certifications {
+			if c.Name != "" {
+				certs = append(certs, c)
+			}
+		}
+		if len(certs) > 0 {
+			section(b, "资格证书")
+			for _, c := range certs {
+				b.WriteString(join(" | ", c.Name, c.Issuer, c.Date) + "\n")
+			}
+		}
+	case models.SectionAwards:
+		var awards []models.Award
+		for _, a := range r.Awards {
+			if a.Title != "" {
+				awards = append(awards, a)
+			}
+		}
+		if len(awards) > 0 {
+			section(b, "荣誉奖项")
+			for _, a := range awards {
+				b.WriteString(join(" | ", a.Title, a.Issuer, a.Date) + "\n")
+				indent(b, a.Description)
+			}
+		}
+	}
+}
+
+func section(b *bytes.Buffer, title string) {
+	b.WriteString("\n" + title + "\n")
+	// CJK characters take two columns in a terminal.
+	width := 0
+	for _, r := range title {
+		if utf8.RuneLen(r) > 1 {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	b.WriteString(strings.Repeat("=", width) + "\n")
+}
+
+func indent(b *bytes.Buffer, s string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return
+	}
+	for _, line := range strings.Split(s, "\n") {
+		b.WriteString("  " + strings.TrimRight(line, " \t") + "\n")
+	}
+}
+
+func join(sep string, parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, sep)
+}
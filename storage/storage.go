@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/dongzhiwei-git/resume/models"
+)
+
+var ErrNotFound = errors.New("resume not found")
+
+// Record is a saved resume. ID is the private key used for editing, Slug is
+// the public part of the share link.
+type Record struct {
+	ID     string        `json:"id"`
+	Slug   string        `json:"slug"`
+	Resume models.Resume `json:"resume"`
+}
+
+var db *sql.DB
+var mu sync.RWMutex
+var mem = map[string]Record{}
+
+func useDB() bool { return db != nil }
+
+func Init(d *sql.DB) error {
+	_, err := d.Exec(`
+        CREATE TABLE IF NOT EXISTS resumes (
+            id CHAR(32) PRIMARY KEY,
+            slug VARCHAR(16) NOT NULL,
+            data MEDIUMTEXT NOT NULL,
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP NULL DEFAULT NULL,
+            UNIQUE KEY uniq_slug (slug)
+        ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+    `)
+	if err != nil {
+		return err
+	}
+	db = d
+	return nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func Create(r models.Resume) (Record, error) {
+	rec := Record{ID: randomHex(16), Slug: randomHex(5), Resume: r}
+	if useDB() {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return Record{}, err
+		}
+		if _, err := db.Exec("INSERT INTO resumes (id, slug, data) VALUES (?, ?, ?)", rec.ID, rec.Slug, string(data)); err != nil {
+			return Record{}, err
+		}
+		return rec, nil
+	}
+	mu.Lock()
+	mem[rec.ID] = rec
+	mu.Unlock()
+	return rec, nil
+}
+
+func Get(id string) (Record, error) {
+	if useDB() {
+		return queryOne("SELECT id, slug, data FROM resumes WHERE id=?", id)
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	rec, ok := mem[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	return rec, nil
+}
+
+func GetBySlug(slug string) (Record, error) {
+	if useDB() {
+		return queryOne("SELECT id, slug, data FROM resumes WHERE slug=?", slug)
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, rec := range mem {
+		if rec.Slug == slug {
+			return rec, nil
+		}
+	}
+	return Record{}, ErrNotFound
+}
+
+func Update(id string, r models.Resume) (Record, error) {
+	if useDB() {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return Record{}, err
+		}
+		if _, err := db.Exec("UPDATE resumes SET data=?, updated_at=NOW() WHERE id=?", string(data), id); err != nil {
+			return Record{}, err
+		}
+		// MySQL reports 0 affected rows for unchanged data, so read back to detect a missing id.
+		return Get(id)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	rec, ok := mem[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	rec.Resume = r
+	mem[id] = rec
+	return rec, nil
+}
+
+func queryOne(q string, arg string) (Record, error) {
+	var rec Record
+	var data string
+	if err := db.QueryRow(q, arg).Scan(&rec.ID, &rec.Slug, &data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Record{}, ErrNotFound
+		}
+		return Record{}, err
+	}
+	if err := json.Unmarshal([]byte(data), &rec.Resume); err != nil {
+		return Record{}, err
+	}
+	return rec, nil
+}
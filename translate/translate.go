@@ -0,0 +1,124 @@
+// Package translate holds the parts of resume translation that do not
+// involve the model: the supported locales and the check that a translated
+// resume kept everything that must stay byte-for-byte identical.
+package translate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dongzhiwei-git/resume/models"
+)
+
+var ErrStructure = errors.New("translation changed the resume structure")
+
+// Locales maps the UI locales (static/i18n/*.json) to the language named in
+// the prompt.
+var Locales = map[string]string{
+	"zh": "简体中文",
+	"en": "English",
+}
+
+type enforcer struct {
+	restored []string
+}
+
+// keep puts orig back into *got if the model changed it.
+func (e *enforcer) keep(field, orig string, got *string) {
+	if *got != orig {
+		*got = orig
+		e.restored = append(e.restored, field)
+	}
+}
+
+func count(what string, orig, got int) error {
+	if orig != got {
+		return fmt.Errorf("%w: %s has %d entries, expected %d", ErrStructure, what, got, orig)
+	}
+	return nil
+}
+
+// Enforce compares a translation with its original. The person's name, the
+// proper nouns (company, school and project names, certificate and award
+// issuers), contact details, the avatar, dates, links and the theme config
+// must not change: any that did are restored from orig and their form paths
+// returned. A translation that
+// added, dropped or merged entries cannot be repaired and yields
+// ErrStructure.
+func Enforce(orig models.Resume, tr *models.Resume) ([]string, error) {
+	checks := []error{
+		count("experience", len(orig.Experience), len(tr.Experience)),
+		count("education", len(orig.Education), len(tr.Education)),
+		count("skills", len(orig.Skills), len(tr.Skills)),
+		count("projects", len(orig.Projects), len(tr.Projects)),
+		count("certifications", len(orig.Certifications), len(tr.Certifications)),
+		count("awards", len(orig.Awards), len(tr.Awards)),
+		count("custom_sections", len(orig.Custom), len(tr.Custom)),
+	}
+	for _, err := range checks {
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, g := range orig.Skills {
+		if err := count(fmt.Sprintf("skills[%d].items", i), len(g.Items), len(tr.Skills[i].Items)); err != nil {
+			return nil, err
+		}
+	}
+	for i, cs := range orig.Custom {
+		if err := count(fmt.Sprintf("custom_sections[%d].items", i), len(cs.Items), len(tr.Custom[i].Items)); err != nil {
+			return nil, err
+		}
+	}
+
+	e := &enforcer{}
+	e.keep("name", orig.Name, &tr.Name)
+	e.keep("email", orig.Email, &tr.Email)
+	e.keep("phone", orig.Phone, &tr.Phone)
+	e.keep("avatar", orig.Avatar, &tr.Avatar)
+	for i, x := range orig.Experience {
+		e.keep(fmt.Sprintf("experience[%d].company", i), x.Company, &tr.Experience[i].Company)
+		e.keep(fmt.Sprintf("experience[%d].date", i), x.Date, &tr.Experience[i].Date)
+	}
+	for i, x := range orig.Education {
+		e.keep(fmt.Sprintf("education[%d].school", i), x.School, &tr.Education[i].School)
+		e.keep(fmt.Sprintf("education[%d].date", i), x.Date, &tr.Education[i].Date)
+	}
+	for i, x := range orig.Projects {
+		e.keep(fmt.Sprintf("projects[%d].name", i), x.Name, &tr.Projects[i].Name)
+		e.keep(fmt.Sprintf("projects[%d].date", i), x.Date, &tr.Projects[i].Date)
+		e.keep(fmt.Sprintf("projects[%d].url", i), x.URL, &tr.Projects[i].URL)
+	}
+	for i, x := range orig.Certifications {
+		e.keep(fmt.Sprintf("certifications[%d].issuer", i), x.Issuer, &tr.Certifications[i].Issuer)
+		e.keep(fmt.Sprintf("certifications[%d].date", i), x.Date, &tr.Certifications[i].Date)
+	}
+	for i, x := range orig.Awards {
+		e.keep(fmt.Sprintf("awards[%d].issuer", i), x.Issuer, &tr.Awards[i].Issuer)
+		e.keep(fmt.Sprintf("awards[%d].date", i), x.Date, &tr.Awards[i].Date)
+	}
+	for i, cs := range orig.Custom {
+		for j, it := range cs.Items {
+			e.keep(fmt.Sprintf("custom_sections[%d].items[%d].date", i, j), it.Date, &tr.Custom[i].Items[j].Date)
+		}
+	}
+	if !sameConfig(orig.Config, tr.Config) {
+		tr.Config = orig.Config
+		e.restored = append(e.restored, "config")
+	}
+	return e.restored, nil
+}
+
+func sameConfig(a, b models.ThemeConfig) bool {
+	if a.Template != b.Template || a.Color != b.Color || a.Font != b.Font ||
+		a.FontSize != b.FontSize || a.PaperSize != b.PaperSize ||
+		len(a.SectionOrder) != len(b.SectionOrder) {
+		return false
+	}
+	for i := range a.SectionOrder {
+		if a.SectionOrder[i] != b.SectionOrder[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package translate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dongzhiwei-git/resume/models"
+)
+
+func TestEnforceRestores(t *testing.T) {
+	orig := models.GetDemoResume()
+	tr := orig
+	tr.Experience = append([]models.Exp(nil), orig.Experience...)
+	tr.Education = append([]models.Edu(nil), orig.Education...)
+	tr.Projects = append([]models.Project(nil), orig.Projects...)
+	tr.Certifications = append([]models.Certification(nil), orig.Certifications...)
+	tr.Awards = append([]models.Award(nil), orig.Awards...)
+	tr.Name = "Zhang San"
+	tr.Summary = "translated summary"
+	tr.Experience[0].Company = "Some Tech Co."
+	tr.Experience[0].Title = "Engineer"
+	tr.Experience[0].Date = "June 2020 - now"
+	tr.Education[0].School = "Some University"
+	tr.Projects[0].Name = "E-commerce Platform"
+	tr.Projects[0].Description = "translated description"
+	tr.Certifications[0].Issuer = "AWS"
+	tr.Awards[0].Issuer = "Some Tech Co."
+	tr.Awards[0].Title = "Outstanding Employee"
+	tr.Config.Color = "#000000"
+
+	restored, err := Enforce(orig, &tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"name", "experience[0].company", "experience[0].date", "education[0].school",
+		"projects[0].name", "certifications[0].issuer", "awards[0].issuer", "config"}
+	if !reflect.DeepEqual(restored, want) {
+		t.Errorf("restored %v, want %v", restored, want)
+	}
+	if tr.Name != orig.Name || tr.Experience[0].Company != orig.Experience[0].Company ||
+		tr.Education[0].School != orig.Education[0].School || tr.Projects[0].Name != orig.Projects[0].Name ||
+		tr.Certifications[0].Issuer != orig.Certifications[0].Issuer || tr.Awards[0].Issuer != orig.Awards[0].Issuer ||
+		tr.Config.Color != orig.Config.Color {
+		t.Errorf("values not restored: %+v", tr)
+	}
+	if tr.Summary != "translated summary" || tr.Experience[0].Title != "Engineer" ||
+		tr.Projects[0].Description != "translated description" || tr.Awards[0].Title != "Outstanding Employee" {
+		t.Error("translated text was reverted")
+	}
+}
+
+func TestEnforceStructure(t *testing.T) {
+	orig := models.GetDemoResume()
+	tr := orig
+	tr.Experience = tr.Experience[:len(tr.Experience)-1]
+	if _, err := Enforce(orig, &tr); !errors.Is(err, ErrStructure) {
+		t.Errorf("dropped entry: %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+// Package usage accounts AI requests and tokens per client and day and
+// defines the daily quotas. Counts are stored in MySQL next to
+// metrics_counters when a database is configured; without one only today's
+// counts are kept, in memory.
+package usage
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dongzhiwei-git/resume/ai"
+)
+
+// Row is the usage of one client on one endpoint during one day.
+type Row struct {
+	Day      string `json:"day"`
+	Client   string `json:"client"`
+	Endpoint string `json:"endpoint"`
+	Requests int64  `json:"requests"`
+	ai.Usage
+}
+
+// Quota limits each client per day. Zero means unlimited.
+type Quota struct {
+	Requests int64 `json:"requests"`
+	Tokens   int64 `json:"tokens"`
+}
+
+// QuotaFromEnv reads AI_DAILY_REQUEST_QUOTA and AI_DAILY_TOKEN_QUOTA.
+func QuotaFromEnv() Quota {
+	return Quota{
+		Requests: envInt("AI_DAILY_REQUEST_QUOTA"),
+		Tokens:   envInt("AI_DAILY_TOKEN_QUOTA"),
+	}
+}
+
+func envInt(key string) int64 {
+	n, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// Exceeded reports whether a client that has already used requests and
+// tokens today may not send another request.
+func (q Quota) Exceeded(requests, tokens int64) bool {
+	return (q.Requests > 0 && requests >= q.Requests) || (q.Tokens > 0 && tokens >= q.Tokens)
+}
+
+// total is a client's usage today across endpoints, which the quota is
+// checked against.
+type total struct {
+	requests, tokens int64
+}
+
+var db *sql.DB
+var mu sync.Mutex
+var mem = map[string]*Row{}
+var memTotals = map[string]*total{}
+var memDay string
+
+func useDB() bool { return db != nil }
+
+func Init(d *sql.DB) error {
+	_, err := d.Exec(`
+        CREATE TABLE IF NOT EXISTS ai_usage (
+            day DATE NOT NULL,
+            client VARCHAR(64) NOT NULL,
+            endpoint VARCHAR(64) NOT NULL,
+            requests BIGINT NOT NULL DEFAULT 0,
+            prompt_tokens BIGINT NOT NULL DEFAULT 0,
+            completion_tokens BIGINT NOT NULL DEFAULT 0,
+            total_tokens BIGINT NOT NULL DEFAULT 0,
+            updated_at TIMESTAMP NULL DEFAULT NULL,
+            PRIMARY KEY (day, client, endpoint)
+        ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+    `)
+	if err != nil {
+		return err
+	}
+	// ai_quota keeps one row per client and day so that checking the quota
+	// and counting a request is a single conditional UPDATE.
+	_, err = d.Exec(`
+        CREATE TABLE IF NOT EXISTS ai_quota (
+            day DATE NOT NULL,
+            client VARCHAR(64) NOT NULL,
+            requests BIGINT NOT NULL DEFAULT 0,
+            total_tokens BIGINT NOT NULL DEFAULT 0,
+            PRIMARY KEY (day, client)
+        ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+    `)
+	if err != nil {
+		return err
+	}
+	// Carry over today's usage recorded before ai_quota existed.
+	_, err = d.Exec(`INSERT IGNORE INTO ai_quota (day, client, requests, total_tokens)
+        SELECT day, client, SUM(requests), SUM(total_tokens) FROM ai_usage WHERE day=? GROUP BY day, client`, Today())
+	if err != nil {
+		return err
+	}
+	db = d
+	return nil
+}
+
+// Today is the accounting day, in server local time.
+func Today() string { return time.Now().Format("2006-01-02") }
+
+// Reserve counts a request of client against q unless that would exceed it,
+// checking and counting atomically so that concurrent requests cannot
+// overrun the quota together. When ok is false nothing was counted, and
+// requests and tokens are the client's usage today. If the database fails,
+// the request is let through.
+func Reserve(client string, q Quota) (requests, tokens int64, ok bool) {
+	day := Today()
+	if useDB() {
+		if _, err := db.Exec("INSERT IGNORE INTO ai_quota (day, client) VALUES (?, ?)", day, client); err != nil {
+			log.Printf("ai usage db err: %v", err)
+			return 0, 0, true
+		}
+		res, err := db.Exec(`UPDATE ai_quota SET requests=requests+1
+            WHERE day=? AND client=? AND (?=0 OR requests<?) AND (?=0 OR total_tokens<?)`,
+			day, client, q.Requests, q.Requests, q.Tokens, q.Tokens)
+		if err != nil {
+			log.Printf("ai usage db err: %v", err)
+			return 0, 0, true
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			return 0, 0, true
+		}
+		err = db.QueryRow("SELECT requests, total_tokens FROM ai_quota WHERE day=? AND client=?", day, client).Scan(&requests, &tokens)
+		if err != nil {
+			log.Printf("ai usage db err: %v", err)
+		}
+		return requests, tokens, false
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	resetDay(day)
+	t, found := memTotals[client]
+	if !found {
+		t = &total{}
+		memTotals[client] = t
+	}
+	if q.Exceeded(t.requests, t.tokens) {
+		return t.requests, t.tokens, false
+	}
+	t.requests++
+	return 0, 0, true
+}
+
+// resetDay drops the in-memory usage of earlier days; callers hold mu.
+func resetDay(day string) {
+	if memDay != day {
+		mem = map[string]*Row{}
+		memTotals = map[string]*total{}
+		memDay = day
+	}
+}
+
+// Add records one request of client to endpoint and the tokens it used. The
+// request itself was counted against the quota by Reserve; the tokens are
+// added to it here.
+func Add(client, endpoint string, u ai.Usage) {
+	day := Today()
+	if useDB() {
+		if u.TotalTokens > 0 {
+			if _, err := db.Exec("UPDATE ai_quota SET total_tokens=total_tokens+? WHERE day=? AND client=?", u.TotalTokens, day, client); err != nil {
+				log.Printf("ai usage db err: %v", err)
+			}
+		}
+		_, err := db.Exec(`INSERT INTO ai_usage (day, client, endpoint, requests, prompt_tokens, completion_tokens, total_tokens, updated_at)
+            VALUES (?, ?, ?, 1, ?, ?, ?, NOW())
+            ON DUPLICATE KEY UPDATE requests=requests+1, prompt_tokens=prompt_tokens+VALUES(prompt_tokens),
+                completion_tokens=completion_tokens+VALUES(completion_tokens), total_tokens=total_tokens+VALUES(total_tokens), updated_at=NOW()`,
+			day, client, endpoint, u.PromptTokens, u.CompletionTokens, u.TotalTokens)
+		if err != nil {
+			log.Printf("ai usage db err: %v", err)
+		}
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	resetDay(day)
+	if t, ok := memTotals[client]; ok {
+		t.tokens += int64(u.TotalTokens)
+	} else {
+		memTotals[client] = &total{tokens: int64(u.TotalTokens)}
+	}
+	key := client + "\x00" + endpoint
+	r, ok := mem[key]
+	if !ok {
+		r = &Row{Day: day, Client: client, Endpoint: endpoint}
+		mem[key] = r
+	}
+	r.Requests++
+	r.Usage.Add(u)
+}
+
+// Day lists the usage rows of day (YYYY-MM-DD), busiest clients first.
+func Day(day string) ([]Row, error) {
+	rows := []Row{}
+	if useDB() {
+		rs, err := db.Query(`SELECT DATE_FORMAT(day, '%Y-%m-%d'), client, endpoint, requests, prompt_tokens, completion_tokens, total_tokens
+            FROM ai_usage WHERE day=? ORDER BY total_tokens DESC, requests DESC`, day)
+		if err != nil {
+			return nil, err
+		}
+		defer rs.Close()
+		for rs.Next() {
+			var r Row
+			if err := rs.Scan(&r.Day, &r.Client, &r.Endpoint, &r.Requests, &r.PromptTokens, &r.CompletionTokens, &r.TotalTokens); err != nil {
+				return nil, err
+			}
+			rows = append(rows, r)
+		}
+		return rows, rs.Err()
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if memDay != day {
+		return rows, nil
+	}
+	for _, r := range mem {
+		rows = append(rows, *r)
+	}
+	sortRows(rows)
+	return rows, nil
+}
+
+func sortRows(rows []Row) {
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.TotalTokens != b.TotalTokens {
+			return a.TotalTokens > b.TotalTokens
+		}
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.Client+a.Endpoint < b.Client+b.Endpoint
+	})
+}
@@ -0,0 +1,57 @@
+package usage
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dongzhiwei-git/resume/ai"
+)
+
+func TestReserveConcurrent(t *testing.T) {
+	q := Quota{Requests: 10}
+	var granted int64
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, ok := Reserve("ip:192.0.2.10", q); ok {
+				atomic.AddInt64(&granted, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if granted != 10 {
+		t.Errorf("granted %d requests, quota is 10", granted)
+	}
+	requests, _, ok := Reserve("ip:192.0.2.10", q)
+	if ok || requests != 10 {
+		t.Errorf("over quota: ok=%v requests=%d", ok, requests)
+	}
+	if _, _, ok := Reserve("ip:192.0.2.11", q); !ok {
+		t.Error("another client was refused")
+	}
+}
+
+func TestReserveTokens(t *testing.T) {
+	q := Quota{Tokens: 100}
+	client := "key:tokens"
+	if _, _, ok := Reserve(client, q); !ok {
+		t.Fatal("first request refused")
+	}
+	Add(client, "ask", ai.Usage{PromptTokens: 80, CompletionTokens: 40, TotalTokens: 120})
+	requests, tokens, ok := Reserve(client, q)
+	if ok || requests != 1 || tokens != 120 {
+		t.Errorf("after the token quota: ok=%v requests=%d tokens=%d", ok, requests, tokens)
+	}
+	rows, err := Day(Today())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range rows {
+		if r.Client == client && (r.Endpoint != "ask" || r.Requests != 1 || r.TotalTokens != 120) {
+			t.Errorf("row: %+v", r)
+		}
+	}
+}
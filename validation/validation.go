@@ -0,0 +1,299 @@
+// Package validation checks a models.Resume before it is rendered or
+// exported. Errors carry the same field paths the editor form uses
+// (e.g. "experience[2].date"), so the front end can highlight the input.
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dongzhiwei-git/resume/models"
+)
+
+// Error codes.
+const (
+	CodeRequired  = "required"
+	CodeTooLong   = "too_long"
+	CodeInvalid   = "invalid"
+	CodeDateOrder = "date_order"
+)
+
+const (
+	maxName = 64
+	maxLine = 200
+	maxText = 4000
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+var (
+	emailRe = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+	phoneRe = regexp.MustCompile(`^[0-9+\-() ]{5,32}$`)
+	colorRe = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+	// A year optionally followed directly by a month, e.g. 2020, 2020.06,
+	// 2020-6, 2020年6月. A space before the month is not accepted so that
+	// "2014 - 2018" is read as two years.
+	dateRe = regexp.MustCompile(`(\d{4})(?:[./年-](\d{1,2})(?:\D|$))?`)
+)
+
+var (
+	templates  = []string{"classic", "modern", "minimal"}
+	paperSizes = []string{"a4", "letter"}
+	fontSizes  = []string{"small", "medium", "large"}
+)
+
+type checker struct {
+	errs  Errors
+	draft bool // skip required-field checks
+}
+
+func (c *checker) add(field, code, msg string) {
+	c.errs = append(c.errs, FieldError{Field: field, Code: code, Message: msg})
+}
+
+func (c *checker) required(field, val, label string) {
+	if !c.draft && strings.TrimSpace(val) == "" {
+		c.add(field, CodeRequired, "请填写"+label)
+	}
+}
+
+func (c *checker) length(field, val string, max int) {
+	if utf8.RuneCountInString(val) > max {
+		c.add(field, CodeTooLong, fmt.Sprintf("不能超过 %d 个字符", max))
+	}
+}
+
+func (c *checker) oneOf(field, val string, allowed []string) {
+	if val == "" {
+		return
+	}
+	for _, a := range allowed {
+		if val == a {
+			return
+		}
+	}
+	c.add(field, CodeInvalid, "可选值为 "+strings.Join(allowed, "、"))
+}
+
+func (c *checker) url(field, val string) {
+	if val == "" {
+		return
+	}
+	u, err := url.Parse(val)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.add(field, CodeInvalid, "链接需以 http:// 或 https:// 开头")
+	}
+}
+
+// date accepts free-form dates and ranges such as "2020.06 - 至今" or
+// "2014 - 2018" as long as every year and month is plausible and a range does
+// not end before it starts.
+func (c *checker) date(field, val string) {
+	if strings.TrimSpace(val) == "" {
+		return
+	}
+	c.length(field, val, maxLine)
+	ms := dateRe.FindAllStringSubmatch(val, -1)
+	if len(ms) == 0 {
+		c.add(field, CodeInvalid, "日期需包含四位年份，例如 2020.06 - 至今")
+		return
+	}
+	var points []int
+	for _, m := range ms {
+		y, _ := strconv.Atoi(m[1])
+		mon := 0
+		if m[2] != "" {
+			mon, _ = strconv.Atoi(m[2])
+		}
+		if y < 1900 || y > 2100 || (m[2] != "" && (mon < 1 || mon > 12)) {
+			bad := m[1]
+			if m[2] != "" {
+				bad += "." + m[2]
+			}
+			c.add(field, CodeInvalid, "日期不合法："+bad)
+			return
+		}
+		points = append(points, y*100+mon)
+	}
+	if len(points) >= 2 && points[1] < points[0] {
+		// Compare at year precision when either side has no month.
+		if points[0]%100 == 0 || points[1]%100 == 0 {
+			if points[1]/100 >= points[0]/100 {
+				return
+			}
+		}
+		c.add(field, CodeDateOrder, "结束时间早于开始时间")
+	}
+}
+
+func empty(vals ...string) bool {
+	for _, v := range vals {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate returns every problem found in r, or nil if there are none.
+// Entries whose fields are all blank (such as the editor's initial empty
+// row) are ignored.
+func Validate(r models.Resume) Errors {
+	return validate(r, false)
+}
+
+// ValidateDraft is Validate without the required-field checks, for previews
+// of a resume that is still being filled in or generated.
+func ValidateDraft(r models.Resume) Errors {
+	return validate(r, true)
+}
+
+func validate(r models.Resume, draft bool) Errors {
+	c := &checker{draft: draft}
+
+	c.required("name", r.Name, "姓名")
+	c.length("name", r.Name, maxName)
+	if r.Email != "" && !emailRe.MatchString(r.Email) {
+		c.add("email", CodeInvalid, "邮箱格式不正确")
+	}
+	c.length("email", r.Email, maxLine)
+	if r.Phone != "" && !phoneRe.MatchString(r.Phone) {
+		c.add("phone", CodeInvalid, "电话只能包含数字、空格和 + - ( )")
+	}
+	if r.Avatar != "" && !strings.HasPrefix(r.Avatar, "/static/uploads/") {
+		c.url("avatar", r.Avatar)
+	}
+	c.length("summary", r.Summary, maxText)
+
+	if r.Config.Color != "" && !colorRe.MatchString(r.Config.Color) {
+		c.add("config.color", CodeInvalid, "颜色需为 #RGB 或 #RRGGBB 格式")
+	}
+	c.oneOf("config.template", r.Config.Template, templates)
+	c.oneOf("config.paper_size", r.Config.PaperSize, paperSizes)
+	c.oneOf("config.font_size", r.Config.FontSize, fontSizes)
+	c.length("config.font", r.Config.Font, maxLine)
+	sectionOrder(c, r)
+
+	for i, e := range r.Experience {
+		if empty(e.Title, e.Company, e.Date, e.Description) {
+			continue
+		}
+		p := fmt.Sprintf("experience[%d]", i)
+		c.required(p+".company", e.Company, "公司名称")
+		c.length(p+".company", e.Company, maxLine)
+		c.length(p+".title", e.Title, maxLine)
+		c.date(p+".date", e.Date)
+		c.length(p+".description", e.Description, maxText)
+	}
+	for i, e := range r.Education {
+		if empty(e.Degree, e.School, e.Date) {
+			continue
+		}
+		p := fmt.Sprintf("education[%d]", i)
+		c.required(p+".school", e.School, "学校名称")
+		c.length(p+".school", e.School, maxLine)
+		c.length(p+".degree", e.Degree, maxLine)
+		c.date(p+".date", e.Date)
+	}
+	for i, g := range r.Skills {
+		p := fmt.Sprintf("skills[%d]", i)
+		c.length(p+".category", g.Category, maxLine)
+		for j, it := range g.Items {
+			ip := fmt.Sprintf("%s.items[%d]", p, j)
+			if it.Level != "" {
+				c.required(ip+".name", it.Name, "技能名称")
+			}
+			c.length(ip+".name", it.Name, maxLine)
+			c.length(ip+".level", it.Level, maxLine)
+		}
+	}
+	for i, pr := range r.Projects {
+		if empty(pr.Name, pr.Role, pr.Date, pr.URL, pr.Description) {
+			continue
+		}
+		p := fmt.Sprintf("projects[%d]", i)
+		c.required(p+".name", pr.Name, "项目名称")
+		c.length(p+".name", pr.Name, maxLine)
+		c.length(p+".role", pr.Role, maxLine)
+		c.date(p+".date", pr.Date)
+		c.url(p+".url", pr.URL)
+		c.length(p+".description", pr.Description, maxText)
+	}
+	for i, ct := range r.Certifications {
+		if empty(ct.Name, ct.Issuer, ct.Date) {
+			continue
+		}
+		p := fmt.Sprintf("certifications[%d]", i)
+		c.required(p+".name", ct.Name, "证书名称")
+		c.length(p+".name", ct.Name, maxLine)
+		c.length(p+".issuer", ct.Issuer, maxLine)
+		c.date(p+".date", ct.Date)
+	}
+	for i, a := range r.Awards {
+		if empty(a.Title, a.Issuer, a.Date, a.Description) {
+			continue
+		}
+		p := fmt.Sprintf("awards[%d]", i)
+		c.required(p+".title", a.Title, "奖项名称")
+		c.length(p+".title", a.Title, maxLine)
+		c.length(p+".issuer", a.Issuer, maxLine)
+		c.date(p+".date", a.Date)
+		c.length(p+".description", a.Description, maxText)
+	}
+	for i, cs := range r.Custom {
+		p := fmt.Sprintf("custom_sections[%d]", i)
+		if len(cs.Items) > 0 {
+			c.required(p+".title", cs.Title, "栏目标题")
+		}
+		c.length(p+".title", cs.Title, maxLine)
+		for j, it := range cs.Items {
+			ip := fmt.Sprintf("%s.items[%d]", p, j)
+			c.length(ip+".heading", it.Heading, maxLine)
+			c.length(ip+".subtitle", it.Subtitle, maxLine)
+			c.date(ip+".date", it.Date)
+			c.length(ip+".body", it.Body, maxText)
+		}
+	}
+	return c.errs
+}
+
+func sectionOrder(c *checker, r models.Resume) {
+	for _, k := range r.Config.SectionOrder {
+		k = strings.TrimSpace(k)
+		if strings.HasPrefix(k, models.SectionCustom+".") {
+			i, err := strconv.Atoi(strings.TrimPrefix(k, models.SectionCustom+"."))
+			if err != nil || i < 0 || i >= len(r.Custom) {
+				c.add("config.section_order", CodeInvalid, "不存在的自定义栏目："+k)
+			}
+			continue
+		}
+		known := false
+		for _, d := range models.DefaultSectionOrder {
+			if k == d {
+				known = true
+				break
+			}
+		}
+		if !known {
+			c.add("config.section_order", CodeInvalid, "未知栏目："+k)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/dongzhiwei-git/resume/models"
+)
+
+func codes(errs Errors) map[string]string {
+	m := map[string]string{}
+	for _, fe := range errs {
+		m[fe.Field] = fe.Code
+	}
+	return m
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		resume models.Resume
+		want   map[string]string // field -> code
+	}{
+		{"demo", models.GetDemoResume(), map[string]string{}},
+		{"empty", models.Resume{}, map[string]string{"name": CodeRequired}},
+		{"blank entry ignored", models.Resume{Name: "张三", Experience: []models.Exp{{}}}, map[string]string{}},
+		{"entry without company", models.Resume{Name: "张三", Experience: []models.Exp{{Title: "工程师"}}},
+			map[string]string{"experience[0].company": CodeRequired}},
+		{"bad contact", models.Resume{Name: "张三", Email: "x@", Phone: "abc"},
+			map[string]string{"email": CodeInvalid, "phone": CodeInvalid}},
+		{"bad config", models.Resume{Name: "张三", Config: models.ThemeConfig{Color: "red", Template: "fancy"}},
+			map[string]string{"config.color": CodeInvalid, "config.template": CodeInvalid}},
+		{"bad date", models.Resume{Name: "张三", Education: []models.Edu{{School: "大学", Date: "2020.13"}}},
+			map[string]string{"education[0].date": CodeInvalid}},
+		{"date order", models.Resume{Name: "张三", Education: []models.Edu{{School: "大学", Date: "2018 - 2014"}}},
+			map[string]string{"education[0].date": CodeDateOrder}},
+		{"unknown section", models.Resume{Name: "张三", Config: models.ThemeConfig{SectionOrder: []string{"hobbies", "custom.0"}}},
+			map[string]string{"config.section_order": CodeInvalid}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := codes(Validate(tt.resume))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for f, code := range tt.want {
+				if got[f] != code {
+					t.Errorf("%s: got %q, want %q (all: %v)", f, got[f], code, got)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateDraft(t *testing.T) {
+	r := models.Resume{Experience: []models.Exp{{Title: "工程师"}}, Email: "x@"}
+	got := codes(ValidateDraft(r))
+	if len(got) != 1 || got["email"] != CodeInvalid {
+		t.Fatalf("got %v, want only the email error", got)
+	}
+	if len(Validate(r)) != 3 {
+		t.Fatalf("Validate should still report required fields: %v", Validate(r))
+	}
+}
+
+func TestDates(t *testing.T) {
+	for _, val := range []string{"2020", "2020.06", "2020-06", "2020年6月", "2020.06 - 至今", "2014 - 2018", "2014.09 - 2018.06"} {
+		c := &checker{}
+		c.date("d", val)
+		if len(c.errs) > 0 {
+			t.Errorf("%q: unexpected %v", val, c.errs)
+		}
+	}
+	for _, val := range []string{"去年", "1800", "2020.00", "2019.06 - 2019.01"} {
+		c := &checker{}
+		c.date("d", val)
+		if len(c.errs) == 0 {
+			t.Errorf("%q: expected an error", val)
+		}
+	}
+}